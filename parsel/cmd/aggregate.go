@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+// aggregator groups records by the value of some fields and aggregates
+// fields over each group
+type aggregator struct {
+	groupBy    fieldList
+	aggregates []aggregate
+	groups     map[string]*group
+	order      []*group
+}
+
+// aggregate is a function, count, sum, avg, min or max, of a field
+type aggregate struct {
+	name  string
+	field parsel.FieldRef
+}
+
+type group struct {
+	key    []string
+	count  int
+	values []aggregated
+}
+
+type aggregated struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// parseAggregates parses group by fields such as 3 or 1,-1 and aggregates
+// such as count,sum:5,avg:5,max:5, fields can only be names when named
+func parseAggregates(groupBy, aggregates string, named bool) (*aggregator, error) {
+	fields, err := parseFields(groupBy, named)
+	if err != nil {
+		return nil, err
+	}
+	a := &aggregator{groupBy: fields, groups: make(map[string]*group)}
+	if aggregates == "" {
+		aggregates = "count"
+	}
+	for _, agg := range strings.Split(aggregates, ",") {
+		colon := strings.Index(agg, ":")
+		if agg == "count" {
+			a.aggregates = append(a.aggregates, aggregate{name: agg})
+			continue
+		}
+		if colon < 0 {
+			return nil, fmt.Errorf("missing field for aggregate %s", agg)
+		}
+		name := agg[0:colon]
+		switch name {
+		case "sum", "avg", "min", "max":
+		default:
+			return nil, fmt.Errorf("unknown aggregate %s (must be count, sum, avg, min or max)", name)
+		}
+		field, err := parsel.ParseFieldRef(agg[colon+1:], named)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse field index %s: %v", agg[colon+1:], err)
+		}
+		if field.Time {
+			return nil, fmt.Errorf("Invalid index, 0 is for date and can not be aggregated")
+		}
+		a.aggregates = append(a.aggregates, aggregate{name: name, field: field})
+	}
+	return a, nil
+}
+
+func (a *aggregator) add(r parsel.Record) {
+	var key []string
+	for _, field := range a.groupBy.expand(r) {
+		if field == 0 {
+			key = append(key, r.Time.Format(time.RFC3339))
+			continue
+		}
+		fieldIndex := field - 1
+		if field < 0 {
+			fieldIndex = len(r.Fields) + field
+		}
+		if fieldIndex >= 0 && fieldIndex < len(r.Fields) {
+			key = append(key, string(r.Fields[fieldIndex]))
+		} else {
+			key = append(key, "")
+		}
+	}
+	mapKey := strings.Join(key, "\x00")
+	g, ok := a.groups[mapKey]
+	if !ok {
+		g = &group{key: key, values: make([]aggregated, len(a.aggregates))}
+		a.groups[mapKey] = g
+		a.order = append(a.order, g)
+	}
+	g.count = g.count + 1
+	for i, agg := range a.aggregates {
+		if agg.name == "count" {
+			continue
+		}
+		fieldIndex := agg.field.Resolve(r)
+		if fieldIndex < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(string(r.Fields[fieldIndex]), 64)
+		if err != nil {
+			continue
+		}
+		v := &g.values[i]
+		if v.count == 0 {
+			v.min = value
+			v.max = value
+		}
+		v.count = v.count + 1
+		v.sum = v.sum + value
+		v.min = math.Min(v.min, value)
+		v.max = math.Max(v.max, value)
+	}
+}
+
+// write writes one row per group, in the order the groups were first seen
+func (a *aggregator) write(delimiter string, out *bufio.Writer) {
+	for _, g := range a.order {
+		row := append([]string(nil), g.key...)
+		for i, agg := range a.aggregates {
+			v := g.values[i]
+			switch {
+			case agg.name == "count":
+				row = append(row, strconv.Itoa(g.count))
+			case v.count == 0:
+				row = append(row, "")
+			case agg.name == "sum":
+				row = append(row, formatNumber(v.sum))
+			case agg.name == "avg":
+				row = append(row, formatNumber(v.sum/float64(v.count)))
+			case agg.name == "min":
+				row = append(row, formatNumber(v.min))
+			case agg.name == "max":
+				row = append(row, formatNumber(v.max))
+			}
+		}
+		out.WriteString(strings.Join(row, delimiter))
+		out.WriteString("\n")
+	}
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
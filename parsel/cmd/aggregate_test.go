@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	rows := []string{"GET 200 10", "POST 500 20", "GET 500 x", "GET 200 30"}
+	testAggregate(t, "1", "", rows, "GET 3\nPOST 1\n")
+	testAggregate(t, "1", "count,sum:3,avg:3,min:-1,max:-1", rows, "GET 3 40 20 10 30\nPOST 1 20 20 20 20\n")
+	testAggregate(t, "1,2", "count", rows, "GET 200 2\nPOST 500 1\nGET 500 1\n")
+	testAggregate(t, "", "count,max:3", rows, "4 30\n")
+	testAggregate(t, "2", "sum:3", rows[2:3], "500 \n")
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	for _, agg := range []string{"sum", "median:1", "sum:0", "sum:"} {
+		if _, err := parseAggregates("1", agg, false); err == nil {
+			t.Error("Expected error for", agg)
+		}
+	}
+}
+
+func testAggregate(t *testing.T, groupBy, aggregates string, rows []string, expect string) {
+	a, err := parseAggregates(groupBy, aggregates, false)
+	if err != nil {
+		t.Fatal("Invalid aggregates", err)
+	}
+	for _, row := range rows {
+		r := parseRecord(t, "2017-03-01T16:02:04Z "+row)
+		a.add(r)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	a.write(" ", buf)
+	buf.Flush()
+	if b.String() != expect {
+		t.Errorf("Expected %q but got %q", expect, b.String())
+	}
+}
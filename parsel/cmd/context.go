@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+// contextLines passes records around matching records on, like grep -A, -B
+// and -C. A record is context if it is within the number of records or the
+// time of a match.
+type contextLines struct {
+	before     int
+	after      int
+	beforeTime time.Duration
+	afterTime  time.Duration
+
+	buffer     []contextRec
+	afterLeft  int
+	afterUntil time.Time
+	seq        int
+	lastSeq    int
+	emitted    bool
+}
+
+type contextRec struct {
+	rec  parsel.Record
+	file string
+	seq  int
+}
+
+// add handles the next record, emitting it and its context. separate is
+// called between groups of records that are not next to each other.
+func (c *contextLines) add(r parsel.Record, file string, match bool, emit func(parsel.Record, string) bool, separate func()) bool {
+	c.seq = c.seq + 1
+	if match {
+		c.prune(r.Time)
+		first := c.seq
+		if len(c.buffer) > 0 {
+			first = c.buffer[0].seq
+		}
+		if c.emitted && first > c.lastSeq+1 {
+			separate()
+		}
+		c.emitted = true
+		c.lastSeq = c.seq
+		c.afterLeft = c.after
+		c.afterUntil = r.Time.Add(c.afterTime)
+		buffer := c.buffer
+		c.buffer = c.buffer[0:0]
+		for _, b := range buffer {
+			if !emit(b.rec, b.file) {
+				return false
+			}
+		}
+		return emit(r, file)
+	}
+	if c.emitted && (c.afterLeft > 0 || (c.afterTime > 0 && !r.Time.After(c.afterUntil))) {
+		if c.afterLeft > 0 {
+			c.afterLeft = c.afterLeft - 1
+		}
+		c.lastSeq = c.seq
+		return emit(r, file)
+	}
+	if c.before > 0 || c.beforeTime > 0 {
+		c.buffer = append(c.buffer, contextRec{r.Copy(), file, c.seq})
+		c.prune(r.Time)
+	}
+	return true
+}
+
+// reset forgets the records of the previous file, so that they are neither
+// context of the next file nor next to its records
+func (c *contextLines) reset() {
+	c.buffer = c.buffer[0:0]
+	c.afterLeft = 0
+	c.afterUntil = time.Time{}
+	c.seq = c.seq + 1
+}
+
+// prune drops buffered records that are not context of a record at now
+func (c *contextLines) prune(now time.Time) {
+	drop := 0
+	for drop < len(c.buffer) && len(c.buffer)-drop > c.before {
+		if c.beforeTime > 0 && !c.buffer[drop].rec.Time.Before(now.Add(-c.beforeTime)) {
+			break
+		}
+		drop = drop + 1
+	}
+	c.buffer = c.buffer[drop:]
+}
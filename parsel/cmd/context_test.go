@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+func contextRun(c *contextLines, lines []string, match string) string {
+	var res []string
+	start := time.Date(2017, 2, 13, 9, 0, 0, 0, time.UTC)
+	emit := func(r parsel.Record, _ string) bool {
+		res = append(res, string(r.Line))
+		return true
+	}
+	separate := func() {
+		res = append(res, "--")
+	}
+	for i, line := range lines {
+		r := parsel.Record{Time: start.Add(time.Duration(i) * time.Second), Line: []byte(line)}
+		c.add(r, "", strings.Contains(line, match), emit, separate)
+	}
+	return strings.Join(res, ",")
+}
+
+func TestContextBefore(t *testing.T) {
+	res := contextRun(&contextLines{before: 1}, strings.Split("a,b,x,c,d,e,x", ","), "x")
+	if res != "b,x,--,e,x" {
+		t.Error("Expected b,x,--,e,x but got", res)
+	}
+}
+
+func TestContextAfter(t *testing.T) {
+	res := contextRun(&contextLines{after: 2}, strings.Split("a,x,b,c,d,x,e", ","), "x")
+	if res != "x,b,c,--,x,e" {
+		t.Error("Expected x,b,c,--,x,e but got", res)
+	}
+}
+
+func TestContextOverlapping(t *testing.T) {
+	res := contextRun(&contextLines{before: 1, after: 1}, strings.Split("a,x,b,x,c,d", ","), "x")
+	if res != "a,x,b,x,c" {
+		t.Error("Expected a,x,b,x,c but got", res)
+	}
+}
+
+func TestContextTime(t *testing.T) {
+	c := &contextLines{beforeTime: 2 * time.Second, afterTime: time.Second}
+	res := contextRun(c, strings.Split("a,b,c,d,x,e,f,g", ","), "x")
+	if res != "c,d,x,e" {
+		t.Error("Expected c,d,x,e but got", res)
+	}
+}
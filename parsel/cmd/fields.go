@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+// fieldRange is a single field or a range of fields, negative fields count
+// from the last field. Named fields are single fields.
+type fieldRange struct {
+	from    int
+	to      int
+	single  bool
+	openEnd bool
+	name    string
+}
+
+type fieldList []fieldRange
+
+// parseFields parses a comma separated list of fields, ranges and names, eg
+// 1,-1,3-5,4-,-3--1,http.status, names are only valid when named
+func parseFields(fields string, named bool) (fieldList, error) {
+	if fields == "" {
+		return nil, nil
+	}
+	stringFields := strings.Split(fields, ",")
+	res := make(fieldList, 0, len(stringFields))
+	for _, field := range stringFields {
+		fr, err := parseFieldRange(field, named)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fr)
+	}
+	return res, nil
+}
+
+func parseFieldRange(field string, named bool) (fieldRange, error) {
+	if field != "" && field[0] != '-' && (field[0] < '0' || field[0] > '9') {
+		if !named {
+			return fieldRange{}, fmt.Errorf("could not parse field %s, names need json or logfmt input", field)
+		}
+		return fieldRange{name: field, single: true}, nil
+	}
+	// a leading - is a negative field, the range separator follows the digits
+	separator := 0
+	if strings.HasPrefix(field, "-") {
+		separator = 1
+	}
+	for separator < len(field) && field[separator] >= '0' && field[separator] <= '9' {
+		separator = separator + 1
+	}
+	from, err := strconv.Atoi(field[0:separator])
+	if err != nil {
+		return fieldRange{}, fmt.Errorf("could not parse field %s: %v", field, err)
+	}
+	if separator == len(field) {
+		return fieldRange{from: from, to: from, single: true}, nil
+	}
+	if field[separator] != '-' {
+		return fieldRange{}, fmt.Errorf("could not parse field %s", field)
+	}
+	end := field[separator+1:]
+	if end == "" {
+		return fieldRange{from: from, openEnd: true}, nil
+	}
+	to, err := strconv.Atoi(end)
+	if err != nil {
+		return fieldRange{}, fmt.Errorf("could not parse field range %s: %v", field, err)
+	}
+	return fieldRange{from: from, to: to}, nil
+}
+
+// expand resolves the ranges and names for a record, single fields are kept
+// as is
+func (fl fieldList) expand(r parsel.Record) []int {
+	if len(fl) == 0 {
+		return nil
+	}
+	count := len(r.Fields)
+	res := make([]int, 0, len(fl))
+	for _, fr := range fl {
+		if fr.name != "" {
+			index := parsel.FieldRef{Name: fr.name}.Resolve(r)
+			if index < 0 {
+				// missing fields are out of range
+				index = count
+			}
+			res = append(res, index+1)
+			continue
+		}
+		if fr.single {
+			res = append(res, fr.from)
+			continue
+		}
+		from := resolveField(fr.from, count)
+		to := count
+		if !fr.openEnd {
+			to = resolveField(fr.to, count)
+		}
+		if from <= to || fr.openEnd {
+			for field := from; field <= to && field <= count; field++ {
+				res = append(res, field)
+			}
+		} else {
+			if from > count {
+				from = count
+			}
+			for field := from; field >= to; field-- {
+				res = append(res, field)
+			}
+		}
+	}
+	return res
+}
+
+// resolveField resolves negative fields, fields before the first resolve to
+// the first field
+func resolveField(field, count int) int {
+	if field >= 0 {
+		return field
+	}
+	field = count + field + 1
+	if field < 1 {
+		field = 1
+	}
+	return field
+}
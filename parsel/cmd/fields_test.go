@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+func TestFieldsExpand(t *testing.T) {
+	testFields(t, "", 5, "[]")
+	testFields(t, "1,2", 5, "[1 2]")
+	testFields(t, "-1,0", 5, "[-1 0]")
+	testFields(t, "3-5", 5, "[3 4 5]")
+	testFields(t, "0-2", 5, "[0 1 2]")
+	testFields(t, "3-7", 5, "[3 4 5]")
+	testFields(t, "4-", 5, "[4 5]")
+	testFields(t, "7-", 5, "[]")
+	testFields(t, "-3--1", 5, "[3 4 5]")
+	testFields(t, "-9--4", 5, "[1 2]")
+	testFields(t, "2--2", 5, "[2 3 4]")
+	testFields(t, "-2-", 5, "[4 5]")
+	testFields(t, "5-3,1", 5, "[5 4 3 1]")
+	testFields(t, "-1--3", 5, "[5 4 3]")
+}
+
+func TestFieldsInvalid(t *testing.T) {
+	for _, fields := range []string{"1-a", "1:2", "-", "1,,2", "level"} {
+		if _, err := parseFields(fields, false); err == nil {
+			t.Error("Expected error for", fields)
+		}
+	}
+}
+
+func testFields(t *testing.T, fields string, count int, expect string) {
+	fl, err := parseFields(fields, false)
+	if err != nil {
+		t.Fatal("Invalid fields", fields, err)
+	}
+	r := parsel.Record{Fields: make([][]byte, count)}
+	res := fmt.Sprint(fl.expand(r))
+	if res != expect {
+		t.Error("Expected", expect, "but got", res, "for", fields)
+	}
+}
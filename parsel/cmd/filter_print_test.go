@@ -5,34 +5,90 @@ import (
 	"bytes"
 	"strings"
 	"testing"
-	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
 )
 
 func TestFilterResultIndex(t *testing.T) {
-	fields, err := parseFields("5")
+	fields, err := parseFields("5", false)
 	if err != nil {
 		t.Fatal("Invalid field", err)
 	}
-	filter, err := parseFilter(false, "  ", "5:5")
+	filter, err := parsel.ParseFilters(nil, "  ", false, []string{"5:5"})
 	if err != nil {
 		t.Fatal("Invalid filter", err)
 	}
-	r, err := newReader(strings.NewReader("2006-01-02T15:04:05Z 1 2 3 4 5"), " ", time.Time{}, time.Time{})
+	r, err := parsel.NewReader(strings.NewReader("2006-01-02T15:04:05Z 1 2 3 4 5"), parsel.Options{Delimiter: " "})
 	if err != nil {
 		t.Fatal("Invalid reader", err)
 	}
 	if !r.Read() {
 		t.Fatal("Could not read line")
 	}
-	if !filter(r.rec) {
+	if !filter.Match(r.Record()) {
 		t.Error("Filter didn't match")
 	}
 	b := bytes.Buffer{}
 	buf := bufio.NewWriter(&b)
-	result(r.rec, " ", fields, buf)
+	result(r.Record(), " ", fields.expand(r.Record()), buf)
 	buf.Flush()
 
 	if strings.TrimSpace(b.String()) != "5" {
 		t.Error("Expected 5 but got", b.String())
 	}
 }
+
+func TestJSONInputFilterAndFields(t *testing.T) {
+	filter, err := parsel.ParseFilters(nil, "\t", true, []string{"http.status:>500 or err.msg:~/timeout after \\d+ms/"})
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	fields, err := parseFields("0,level,http.status,missing", true)
+	if err != nil {
+		t.Fatal("Invalid fields", err)
+	}
+	r, err := parsel.NewReader(strings.NewReader(`{"time":"2017-02-13T09:00:00Z","level":"info","http":{"status":200}}
+{"level":"error","time":"2017-02-13T10:00:00Z","http":{"status":503},"err":{"msg":"timeout after 20ms"}}
+not json`), parsel.Options{Input: "json"})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	for r.Read() {
+		if filter.Match(r.Record()) {
+			result(r.Record(), "\t", fields.expand(r.Record()), buf)
+			resultJSON(r.Record(), "", "\t", fields.expand(r.Record()), buf)
+		}
+	}
+	buf.Flush()
+	expect := "2017-02-13T10:00:00Z\terror\t503\n" +
+		`{"time":"2017-02-13T10:00:00Z","level":"error","http.status":"503"}` + "\n"
+	if b.String() != expect {
+		t.Errorf("Expected %q but got %q", expect, b.String())
+	}
+}
+
+func TestMultilineFilterAndResult(t *testing.T) {
+	stackTrace := "2017-02-13T09:00:00Z\tERROR\tfailed\njava.lang.NullPointerException\n\tat Foo.bar(Foo.java:12)\n2017-02-13T09:00:01Z\tINFO\tdone"
+	filter, err := parsel.ParseFilters(nil, "\t", false, []string{"NullPointer"})
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	r, err := parsel.NewReader(strings.NewReader(stackTrace), parsel.Options{Multiline: true})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	for r.Read() {
+		if filter.Match(r.Record()) {
+			result(r.Record(), "\t", nil, buf)
+		}
+	}
+	buf.Flush()
+	expect := strings.Join(strings.Split(stackTrace, "\n")[0:3], "\n") + "\n"
+	if b.String() != expect {
+		t.Errorf("Expected %q but got %q", expect, b.String())
+	}
+}
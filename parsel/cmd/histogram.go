@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+// width of the longest bar
+const barWidth = 40
+
+// most buckets written, including empty buckets
+const maxBuckets = 100000
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// histogram counts records per time bucket, optionally split into one series
+// per value of a field
+type histogram struct {
+	size  time.Duration
+	split bool
+	field parsel.FieldRef
+	// counts by bucket in UTC, records may have different offsets
+	counts map[time.Time]map[string]int
+	series []string
+	seen   map[string]bool
+	first  time.Time
+	last   time.Time
+	// location buckets are written in, the one of the first record
+	location *time.Location
+}
+
+// parseHistogram parses the bucket size and the field to split series by,
+// which can only be a name when named
+func parseHistogram(bucket, splitBy string, named bool) (*histogram, error) {
+	size, err := time.ParseDuration(bucket)
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("Invalid bucket %s (must be of type .*(ns|us|ms|s|m|h))", bucket)
+	}
+	h := &histogram{
+		size:   size,
+		counts: make(map[time.Time]map[string]int),
+		seen:   make(map[string]bool),
+	}
+	if splitBy != "" {
+		field, err := parsel.ParseFieldRef(splitBy, named)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse field index %s: %v", splitBy, err)
+		}
+		if field.Time {
+			return nil, fmt.Errorf("Invalid index, 0 is for date and can not split buckets")
+		}
+		h.split = true
+		h.field = field
+	}
+	return h, nil
+}
+
+func (h *histogram) add(r parsel.Record) {
+	bucket := r.Time.Truncate(h.size).UTC()
+	if h.location == nil {
+		h.location = r.Time.Location()
+	}
+	series := ""
+	if h.split {
+		if fieldIndex := h.field.Resolve(r); fieldIndex >= 0 {
+			series = string(r.Fields[fieldIndex])
+		}
+	}
+	if !h.seen[series] {
+		h.seen[series] = true
+		h.series = append(h.series, series)
+	}
+	counts, ok := h.counts[bucket]
+	if !ok {
+		counts = make(map[string]int)
+		h.counts[bucket] = counts
+	}
+	counts[series] = counts[series] + 1
+	if h.first.IsZero() || bucket.Before(h.first) {
+		h.first = bucket
+	}
+	if bucket.After(h.last) {
+		h.last = bucket
+	}
+}
+
+// buckets returns all buckets from the first to the last, including empty,
+// failing if there are more than maxBuckets
+func (h *histogram) buckets() ([]time.Time, error) {
+	var res []time.Time
+	if len(h.counts) == 0 {
+		return res, nil
+	}
+	if count := h.last.Sub(h.first) / h.size; count >= maxBuckets {
+		return nil, fmt.Errorf("Too many buckets of %s from %s to %s, use a larger bucket or from and to",
+			h.size, h.first.Format(time.RFC3339), h.last.Format(time.RFC3339))
+	}
+	for bucket := h.first; !bucket.After(h.last); bucket = bucket.Add(h.size) {
+		res = append(res, bucket)
+	}
+	return res, nil
+}
+
+// write writes the counts as a table with a bar of the total per bucket,
+// followed by a sparkline per series when split
+func (h *histogram) write(delimiter string, out *bufio.Writer) error {
+	buckets, err := h.buckets()
+	if err != nil {
+		return err
+	}
+	totals := make([]int, len(buckets))
+	max := 0
+	for i, bucket := range buckets {
+		for _, count := range h.counts[bucket] {
+			totals[i] = totals[i] + count
+		}
+		if totals[i] > max {
+			max = totals[i]
+		}
+	}
+
+	if h.split {
+		out.WriteString("time")
+		for _, series := range h.series {
+			out.WriteString(delimiter)
+			out.WriteString(series)
+		}
+		out.WriteString(delimiter)
+		out.WriteString("total\n")
+	}
+	for i, bucket := range buckets {
+		out.WriteString(bucket.In(h.location).Format(time.RFC3339))
+		if h.split {
+			for _, series := range h.series {
+				out.WriteString(delimiter)
+				out.WriteString(strconv.Itoa(h.counts[bucket][series]))
+			}
+		}
+		out.WriteString(delimiter)
+		out.WriteString(strconv.Itoa(totals[i]))
+		out.WriteString(delimiter)
+		out.WriteString(strings.Repeat("#", scale(totals[i], max, barWidth)))
+		out.WriteString("\n")
+	}
+
+	if h.split {
+		out.WriteString("\n")
+		for _, series := range h.series {
+			counts := make([]int, len(buckets))
+			seriesMax := 0
+			for i, bucket := range buckets {
+				counts[i] = h.counts[bucket][series]
+				if counts[i] > seriesMax {
+					seriesMax = counts[i]
+				}
+			}
+			out.WriteString(series)
+			out.WriteString(delimiter)
+			out.WriteString(sparkline(counts, seriesMax))
+			out.WriteString("\n")
+		}
+	}
+	return nil
+}
+
+// scale scales value from 0 to max into 0 to width, non zero values are at
+// least 1
+func scale(value, max, width int) int {
+	if value == 0 || max == 0 {
+		return 0
+	}
+	res := value * width / max
+	if res == 0 {
+		res = 1
+	}
+	return res
+}
+
+func sparkline(counts []int, max int) string {
+	res := make([]rune, 0, len(counts))
+	for _, count := range counts {
+		if count == 0 {
+			res = append(res, ' ')
+		} else {
+			res = append(res, sparks[scale(count, max, len(sparks))-1])
+		}
+	}
+	return string(res)
+}
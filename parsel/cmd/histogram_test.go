@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var histogramRows = []string{
+	"2017-03-01T16:00:10Z ERROR",
+	"2017-03-01T16:00:50Z WARN",
+	"2017-03-01T16:00:55Z ERROR",
+	"2017-03-01T16:02:30Z ERROR",
+}
+
+func TestHistogram(t *testing.T) {
+	testHistogram(t, "1m", "", "2017-03-01T16:00:00Z 3 "+strings.Repeat("#", 40)+"\n"+
+		"2017-03-01T16:01:00Z 0 \n"+
+		"2017-03-01T16:02:00Z 1 "+strings.Repeat("#", 13)+"\n")
+}
+
+func TestHistogramSplit(t *testing.T) {
+	testHistogram(t, "1m", "1", "time ERROR WARN total\n"+
+		"2017-03-01T16:00:00Z 2 1 3 "+strings.Repeat("#", 40)+"\n"+
+		"2017-03-01T16:01:00Z 0 0 0 \n"+
+		"2017-03-01T16:02:00Z 1 0 1 "+strings.Repeat("#", 13)+"\n"+
+		"\n"+
+		"ERROR █ ▄\n"+
+		"WARN █  \n")
+}
+
+func TestHistogramInvalid(t *testing.T) {
+	if _, err := parseHistogram("x", "", false); err == nil {
+		t.Error("Expected error for invalid bucket")
+	}
+	if _, err := parseHistogram("1m", "0", false); err == nil {
+		t.Error("Expected error for time field")
+	}
+}
+
+func TestHistogramOffsets(t *testing.T) {
+	h, err := parseHistogram("1h", "", false)
+	if err != nil {
+		t.Fatal("Invalid histogram", err)
+	}
+	h.add(parseRecord(t, "2017-03-26T01:30:00+01:00 a"))
+	h.add(parseRecord(t, "2017-03-26T03:10:00+02:00 b"))
+	h.add(parseRecord(t, "2017-03-26T04:10:00+02:00 c"))
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	if err := h.write(" ", buf); err != nil {
+		t.Fatal("Could not write histogram", err)
+	}
+	buf.Flush()
+	expect := "2017-03-26T01:00:00+01:00 1 " + strings.Repeat("#", 40) + "\n" +
+		"2017-03-26T02:00:00+01:00 1 " + strings.Repeat("#", 40) + "\n" +
+		"2017-03-26T03:00:00+01:00 1 " + strings.Repeat("#", 40) + "\n"
+	if b.String() != expect {
+		t.Errorf("Expected\n%s\nbut got\n%s", expect, b.String())
+	}
+}
+
+func TestHistogramTooManyBuckets(t *testing.T) {
+	h, err := parseHistogram("1s", "", false)
+	if err != nil {
+		t.Fatal("Invalid histogram", err)
+	}
+	h.add(parseRecord(t, "1970-01-01T00:00:00Z ERROR"))
+	h.add(parseRecord(t, "2017-03-01T16:00:10Z ERROR"))
+	buf := bufio.NewWriter(&bytes.Buffer{})
+	if err := h.write(" ", buf); err == nil {
+		t.Error("Expected error for too many buckets")
+	}
+}
+
+func testHistogram(t *testing.T, bucket, splitBy string, expect string) {
+	h, err := parseHistogram(bucket, splitBy, false)
+	if err != nil {
+		t.Fatal("Invalid histogram", err)
+	}
+	for _, row := range histogramRows {
+		r := parseRecord(t, row)
+		h.add(r)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	if err := h.write(" ", buf); err != nil {
+		t.Fatal("Could not write histogram", err)
+	}
+	buf.Flush()
+	if b.String() != expect {
+		t.Errorf("Expected\n%s\nbut got\n%s", expect, b.String())
+	}
+}
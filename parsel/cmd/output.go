@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+// outputFn writes a record, file is set when records are tagged with the
+// file they came from
+type outputFn func(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer)
+
+func parseOutput(output string) (outputFn, error) {
+	switch strings.ToLower(output) {
+	case "", "text":
+		return resultText, nil
+	case "json":
+		return resultJSON, nil
+	case "csv":
+		return resultSeparated(','), nil
+	case "tsv":
+		return resultSeparated('\t'), nil
+	case "logfmt":
+		return resultLogfmt, nil
+	}
+	return nil, fmt.Errorf("unknown output %s (must be text, json, csv, tsv or logfmt)", output)
+}
+
+func resultText(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer) {
+	if file != "" {
+		out.WriteString(file)
+		out.WriteString(delimiter)
+	}
+	result(r, delimiter, fields, out)
+}
+
+// keyValue is a field of a record in structured output
+type keyValue struct {
+	key   string
+	value string
+}
+
+// keyValues selects the fields of a record the same way as result, keyed by
+// field name or index and time for the timestamp
+func keyValues(r parsel.Record, file string, fields []int) []keyValue {
+	var res []keyValue
+	if file != "" {
+		res = append(res, keyValue{"file", file})
+	}
+	if len(fields) == 0 {
+		res = append(res, keyValue{"time", r.Time.Format(time.RFC3339)})
+		for i, record := range r.Fields {
+			res = append(res, keyValue{fieldKey(r, i), string(record)})
+		}
+		return withContinuation(res, r)
+	}
+	for _, field := range fields {
+		if field == 0 {
+			res = append(res, keyValue{"time", r.Time.Format(time.RFC3339)})
+			continue
+		}
+		fieldIndex := field - 1
+		if field < 0 {
+			fieldIndex = len(r.Fields) + field
+		}
+		if fieldIndex >= 0 && fieldIndex < len(r.Fields) {
+			res = append(res, keyValue{fieldKey(r, fieldIndex), string(r.Fields[fieldIndex])})
+		}
+	}
+	return withContinuation(res, r)
+}
+
+func fieldKey(r parsel.Record, index int) string {
+	if index < len(r.Names) {
+		return string(r.Names[index])
+	}
+	return strconv.Itoa(index + 1)
+}
+
+func withContinuation(kvs []keyValue, r parsel.Record) []keyValue {
+	if len(r.Continuation) == 0 {
+		return kvs
+	}
+	return append(kvs, keyValue{"continuation", string(r.Continuation)})
+}
+
+func resultJSON(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer) {
+	out.WriteString("{")
+	for i, kv := range keyValues(r, file, fields) {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, _ := json.Marshal(kv.key)
+		value, _ := json.Marshal(kv.value)
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}\n")
+}
+
+func resultSeparated(separator rune) outputFn {
+	return func(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer) {
+		kvs := keyValues(r, file, fields)
+		values := make([]string, 0, len(kvs))
+		for _, kv := range kvs {
+			values = append(values, kv.value)
+		}
+		w := csv.NewWriter(out)
+		w.Comma = separator
+		w.Write(values)
+		w.Flush()
+	}
+}
+
+func resultLogfmt(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer) {
+	for i, kv := range keyValues(r, file, fields) {
+		if i > 0 {
+			out.WriteString(" ")
+		}
+		out.WriteString(kv.key)
+		out.WriteString("=")
+		if kv.value == "" || strings.ContainsAny(kv.value, " =\"\t\r\n\\") {
+			out.WriteString(strconv.Quote(kv.value))
+		} else {
+			out.WriteString(kv.value)
+		}
+	}
+	out.WriteString("\n")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jwiklund/tools/parsel/parsel"
+)
+
+func TestOutputJSON(t *testing.T) {
+	testOutput(t, "json", "a b", "", "", `{"time":"2017-03-01T16:02:04Z","1":"a","2":"b"}`)
+	testOutput(t, "json", "a \"b\"", "app.log", "-1,0", `{"file":"app.log","2":"\"b\"","time":"2017-03-01T16:02:04Z"}`)
+}
+
+func TestOutputCSV(t *testing.T) {
+	testOutput(t, "csv", "a b,c", "", "", `2017-03-01T16:02:04Z,a,"b,c"`)
+	testOutput(t, "csv", "a \"b\"", "", "2", `"""b"""`)
+	testOutput(t, "tsv", "a b,c", "", "1-", "a\tb,c")
+}
+
+func TestOutputLogfmt(t *testing.T) {
+	testOutput(t, "logfmt", "a b=c", "", "", `time=2017-03-01T16:02:04Z 1=a 2="b=c"`)
+	testOutput(t, "logfmt", "a", "app.log", "1,5", `file=app.log 1=a`)
+}
+
+func TestOutputUnknown(t *testing.T) {
+	if _, err := parseOutput("xml"); err == nil {
+		t.Error("Expected error for xml")
+	}
+}
+
+func testOutput(t *testing.T, output, row, file, fields, expect string) {
+	r := parseRecord(t, "2017-03-01T16:02:04Z "+row)
+	fl, err := parseFields(fields, false)
+	if err != nil {
+		t.Fatal("Invalid fields", err)
+	}
+	write, err := parseOutput(output)
+	if err != nil {
+		t.Fatal("Invalid output", err)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	write(r, file, " ", fl.expand(r), buf)
+	buf.Flush()
+	if b.String() != expect+"\n" {
+		t.Error("Expected", expect, "but got", b.String())
+	}
+}
+
+func parseRecord(t *testing.T, line string) parsel.Record {
+	r, err := parsel.NewReader(strings.NewReader(line), parsel.Options{Delimiter: " "})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	if !r.Read() {
+		t.Fatal("could not parse line", line)
+	}
+	return r.Record()
+}
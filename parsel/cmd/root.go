@@ -7,33 +7,75 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/jwiklund/tools/parsel/parsel"
 )
 
 type Args struct {
-	From       string
-	To         string
-	Delimiter  string
-	Fields     string
-	Cpuprofile string
-	Filters    []string
-	Preview    bool
-	Verbose    bool
-	Args       []string
+	From         string
+	To           string
+	Delimiter    string
+	TimeFormat   string
+	Input        string
+	TimeKey      string
+	Output       string
+	Format       string
+	GroupBy      string
+	Aggregates   string
+	Bucket       string
+	BucketBy     string
+	Workers      int
+	Multiline    bool
+	Continuation string
+	MaxLine      string
+	LongLines    string
+	Fields       string
+	Cpuprofile   string
+	Filters      []string
+	Joins        []string
+	FilterFile   string
+	ShowPattern  bool
+	Preview      bool
+	Verbose      bool
+	Strict       bool
+	QuietErrors  bool
+	Merge        bool
+	Before       int
+	After        int
+	Context      int
+	ContextTime  string
+	Follow       bool
+	Tag          bool
+	Args         []string
+}
+
+// source is a file read, what was read from it and the time range of its
+// matching records
+type source struct {
+	name      string
+	found     bool
+	firstTime time.Time
+	lastTime  time.Time
+	matched   int
+	stats     parsel.Stats
+	err       error
 }
 
-type rec struct {
-	timestamp time.Time
-	line      []byte
-	records   [][]byte
+func (s *source) seen(t time.Time) {
+	s.matched = s.matched + 1
+	if !s.found {
+		s.found = true
+		s.firstTime = t
+	}
+	s.lastTime = t
 }
 
 func Parsel(args *Args) {
 	if args.Cpuprofile != "" {
 		if args.Verbose {
-			fmt.Println("Storing cpu profile in " + args.Cpuprofile)
+			fmt.Fprintln(os.Stderr, "Storing cpu profile in "+args.Cpuprofile)
 		}
 		f, err := os.Create(args.Cpuprofile)
 		if err != nil {
@@ -59,7 +101,7 @@ func Parsel(args *Args) {
 			return t
 		}
 
-		fmt.Printf("Invalid %s %s duration|time (must be of type .*(ns|us|ms|s|m|h) or RFC3339 ie 2017-02-13T09:16:57Z)\n", what, value)
+		fmt.Fprintf(os.Stderr, "Invalid %s %s duration|time (must be of type .*(ns|us|ms|s|m|h) or RFC3339 ie 2017-02-13T09:16:57Z)\n", what, value)
 		os.Exit(1)
 		return now
 	}
@@ -67,178 +109,346 @@ func Parsel(args *Args) {
 	to := parseDuration("to", args.To)
 
 	if args.Verbose {
-		fmt.Printf("Return records between %s and %s\n", from, to)
+		fmt.Fprintf(os.Stderr, "Return records between %s and %s\n", from, to)
 	}
 
-	fields, err := parseFields(args.Fields)
+	if args.Delimiter == "" {
+		args.Delimiter = parsel.DefaultDelimiter(args.Input)
+	}
+	named := parsel.NamedInput(args.Input)
+	fields, err := parseFields(args.Fields, named)
 	if err != nil {
-		fmt.Println("Invalid fields")
+		fmt.Fprintln(os.Stderr, "Invalid fields:", err)
+		os.Exit(1)
+	}
+	var trace io.Writer
+	if args.Verbose {
+		trace = os.Stderr
+	}
+	filter, err := parsel.ParseFilters(trace, args.Delimiter, named, args.Filters)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var patterns *parsel.Patterns
+	if args.FilterFile != "" {
+		patterns, err = parsel.LoadPatterns(args.FilterFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		filter = filter.And(patterns.Filter(trace))
+	} else if args.ShowPattern {
+		fmt.Fprintln(os.Stderr, "Show pattern needs a filter file")
 		os.Exit(1)
 	}
-	filter, err := parseFilters(args.Verbose, args.Delimiter, args.Filters)
+	var joins []*parsel.Join
+	for _, spec := range args.Joins {
+		j, err := parsel.ParseJoin(spec, named)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		joins = append(joins, j)
+	}
+	options := parsel.Options{
+		From:         from,
+		To:           to,
+		Input:        args.Input,
+		Delimiter:    args.Delimiter,
+		TimeFormat:   args.TimeFormat,
+		TimeKey:      args.TimeKey,
+		Multiline:    args.Multiline,
+		Continuation: args.Continuation,
+		LongLines:    args.LongLines,
+	}
+	if args.MaxLine != "" {
+		options.MaxLine, err = parseSize(args.MaxLine)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid max line:", err)
+			os.Exit(1)
+		}
+	}
+	if err := options.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	write, err := parseOutput(args.Output)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if args.Format != "" {
+		if strings.ToLower(args.Output) != "text" && args.Output != "" {
+			fmt.Fprintln(os.Stderr, "Can not combine format with output", args.Output)
+			os.Exit(1)
+		}
+		write, err = parseTemplate(args.Format, named)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var agg *aggregator
+	if args.GroupBy != "" || args.Aggregates != "" {
+		agg, err = parseAggregates(args.GroupBy, args.Aggregates, named)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var hist *histogram
+	if args.Bucket != "" {
+		if agg != nil {
+			fmt.Fprintln(os.Stderr, "Bucket can not be combined with group by")
+			os.Exit(1)
+		}
+		hist, err = parseHistogram(args.Bucket, args.BucketBy, named)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if args.Follow && (args.Merge || len(args.Args) > 1) {
+		fmt.Fprintln(os.Stderr, "Follow only supports a single file")
 		os.Exit(1)
 	}
 
 	output := bufio.NewWriter(os.Stdout)
-	for _, file := range args.Args {
-		var r *reader
+	open := func(file string) (*parsel.Reader, error) {
+		options := options
+		options.ParseError = func(e *parsel.LineError) error {
+			if args.Strict {
+				return &parsel.LineError{Line: e.Line, Text: append([]byte(nil), e.Text...), Err: e.Err}
+			}
+			if !args.QuietErrors {
+				fmt.Fprintln(os.Stderr, diagnostic(file, e))
+			}
+			return nil
+		}
+		var r *parsel.Reader
 		var err error
 		if file == "-" || file == "stdin" {
-			r, err = newReader(os.Stdin, args.Delimiter, from, to)
+			r, err = parsel.OpenStdin(options)
+		} else if args.Follow {
+			r, err = parsel.Follow(file, options, func() {
+				output.Flush()
+			})
 		} else {
-			r, err = newReaderFile(file, args.Delimiter, from, to)
+			r, err = parsel.Open(file, options)
 		}
-		if err != nil {
-			fmt.Println(err)
-			continue
+		if err == nil && args.Verbose && r.Sorted() {
+			fmt.Fprintf(os.Stderr, "file %s is sorted, seeking\n", file)
 		}
-		first := true
-		var firstTime, lastTime time.Time
-		var count int
-		for r.Read() {
-			if !filter(r.rec) {
+		return r, err
+	}
+
+	var count int
+	emit := func(r parsel.Record, file string) bool {
+		if args.ShowPattern {
+			patterns.AppendMatch(&r)
+		}
+		if agg != nil {
+			agg.add(r)
+			return true
+		}
+		if hist != nil {
+			hist.add(r)
+			return true
+		}
+		expanded := fields.expand(r)
+		if args.Preview {
+			if count == 0 {
+				printFieldIndexes(r, args.Delimiter, expanded, output)
+			}
+			if count > 10 {
+				return false
+			}
+			count = count + 1
+		}
+		if !args.Tag {
+			file = ""
+		}
+		write(r, file, args.Delimiter, expanded, output)
+		return true
+	}
+
+	var ctx *contextLines
+	if (args.Before > 0 || args.After > 0 || args.Context > 0 || args.ContextTime != "") && agg == nil && hist == nil {
+		ctx = &contextLines{before: args.Before, after: args.After}
+		if args.Context > 0 {
+			ctx.before = args.Context
+			ctx.after = args.Context
+		}
+		if args.ContextTime != "" {
+			d, err := time.ParseDuration(args.ContextTime)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid context time %s (must be of type .*(ns|us|ms|s|m|h))\n", args.ContextTime)
+				os.Exit(1)
+			}
+			ctx.beforeTime = d
+			ctx.afterTime = d
+		}
+	}
+	separate := func() {
+		if (strings.ToLower(args.Output) == "text" || args.Output == "") && args.Format == "" {
+			output.WriteString("--\n")
+		}
+	}
+	handle := func(r parsel.Record, s *source) bool {
+		match := filter.Match(r) && join(joins, &r)
+		if match {
+			s.seen(r.Time)
+		}
+		if ctx != nil {
+			return ctx.add(r, s.name, match, emit, separate)
+		}
+		return !match || emit(r, s.name)
+	}
+
+	var sources []*source
+	// failed is set when a file could not be opened
+	failed := false
+	if args.Merge {
+		var readers []*parsel.Reader
+		for _, file := range args.Args {
+			r, err := open(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
 				continue
 			}
-			if first {
-				first = false
-				firstTime = r.rec.timestamp
+			sources = append(sources, &source{name: file})
+			readers = append(readers, r)
+		}
+		m := parsel.NewMergeReader(readers)
+		for m.Read() {
+			if !handle(m.Record(), sources[m.Index()]) {
+				break
 			}
-			if args.Preview {
-				if count == 0 {
-					printFieldIndexes(r.rec, args.Delimiter, fields, output)
-				}
-				if count > 10 {
-					break
+		}
+		if m.Err() != nil {
+			sources[m.Index()].err = m.Err()
+		}
+		for i, s := range sources {
+			s.stats = readers[i].Stats()
+			readers[i].Close()
+		}
+	} else {
+		for _, file := range args.Args {
+			r, err := open(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				continue
+			}
+			s := &source{name: file}
+			sources = append(sources, s)
+			count = 0
+			filter.Reset()
+			if ctx != nil {
+				ctx.reset()
+			}
+			// context needs all records
+			if args.Workers > 1 && ctx == nil {
+				s.err = r.ReadParallel(args.Workers, filter, func(record parsel.Record) bool {
+					if !join(joins, &record) {
+						return true
+					}
+					s.seen(record.Time)
+					return emit(record, file)
+				})
+			} else {
+				for r.Read() {
+					if !handle(r.Record(), s) {
+						break
+					}
 				}
-				count = count + 1
+				s.err = r.Err()
+			}
+			s.stats = r.Stats()
+			r.Close()
+			if s.err != nil {
+				break
 			}
-			lastTime = r.rec.timestamp
-			result(r.rec, args.Delimiter, fields, output)
 		}
-		if args.Verbose {
-			fmt.Printf("file %s time %s to %s\n", file, firstTime, lastTime)
+	}
+	if agg != nil {
+		agg.write(args.Delimiter, output)
+	}
+	if hist != nil {
+		if err := hist.write(args.Delimiter, output); err != nil {
+			output.Flush()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 	}
 	output.Flush()
-}
-
-func parseFields(fields string) ([]int, error) {
-	if fields == "" {
-		return nil, nil
-	}
-	stringFields := strings.Split(fields, ",")
-	res := make([]int, 0, len(stringFields))
-	for _, field := range stringFields {
-		fieldNr, err := strconv.Atoi(field)
-		if err != nil {
-			return nil, fmt.Errorf("could not parse field %s: %v", field, err)
-		}
-		res = append(res, fieldNr)
+	if !summary(sources, args.Verbose) || failed {
+		os.Exit(1)
 	}
-	return res, nil
 }
 
-func newReaderFile(file, delimiter string, from, to time.Time) (*reader, error) {
-	f, err := os.OpenFile(file, os.O_RDONLY, 0)
-	if err != nil {
-		return nil, fmt.Errorf("coult not open %s: %s", file, err)
+// diagnostic formats an error reading file, with the line number for lines
+// that could not be parsed
+func diagnostic(file string, err error) string {
+	if e, ok := err.(*parsel.LineError); ok {
+		return fmt.Sprintf("%s:%d: could not parse %q: %s", file, e.Line, e.Text, e.Err)
 	}
-	return newReader(f, delimiter, from, to)
+	return fmt.Sprintf("%s: %s", file, err)
 }
 
-func newReader(r io.Reader, delimiter string, from, to time.Time) (*reader, error) {
-	if len(delimiter) > 1 {
-		return nil, fmt.Errorf("delimiter of size != 1 not supported")
-	}
-	return &reader{
-		scanner:   bufio.NewScanner(r),
-		delimiter: delimiter[0],
-		from:      from,
-		to:        to,
-	}, nil
+// parseSize parses a number of bytes, with an optional unit such as KB or
+// MiB, the same as byte sizes in filters
+func parseSize(value string) (int, error) {
+	n, err := parsel.ParseByteSize(value)
+	return int(n), err
 }
 
-type reader struct {
-	scanner   *bufio.Scanner
-	rec       rec
-	delimiter byte
-	from      time.Time
-	to        time.Time
-}
-
-func (r *reader) Read() bool {
-	more, found := r.readInternal()
-	for more && !found {
-		more, found = r.readInternal()
-	}
-	return found
-}
-
-var zero time.Time
-
-func (r *reader) readInternal() (bool, bool) {
-	if !r.scanner.Scan() {
-		return false, false
-	}
-	if len(r.scanner.Bytes()) == 0 {
-		return true, false
-	}
-	err := parse(r.delimiter, r.scanner.Bytes(), &r.rec)
-	if err != nil {
-		fmt.Printf("Could not parse line %s: %s\n", r.scanner.Text(), err)
-		return true, false
-	}
-	if !r.from.After(r.rec.timestamp) || r.from == zero {
-		if r.rec.timestamp.Before(r.to) || r.to == zero {
-			return true, true
+// summary prints what was read from each source on stderr if verbose or if
+// lines could not be read as they are, it returns false if any source failed
+func summary(sources []*source, verbose bool) bool {
+	ok := true
+	errors := 0
+	for _, s := range sources {
+		errors = errors + s.stats.Errors + s.stats.Long
+		if s.err != nil {
+			fmt.Fprintln(os.Stderr, diagnostic(s.name, s.err))
+			ok = false
 		}
 	}
-	return true, false
-}
-
-func parse(delimiter byte, line []byte, rec *rec) error {
-	var last int
-	var err error
-	last, rec.timestamp, err = parseTime(delimiter, line)
-	if err != nil {
-		return err
+	if !verbose && errors == 0 {
+		return ok
 	}
-	rec.line = line
-	recs := rec.records[0:0]
-	current := 1
-	for last+current < len(line) {
-		if line[last+current] == delimiter {
-			recs = append(recs, line[last:last+current])
-			last = last + current + 1
-			current = 0
+	for _, s := range sources {
+		fmt.Fprintf(os.Stderr, "file %s: %d lines read, %d matched, %d skipped, %d errors",
+			s.name, s.stats.Lines, s.matched, s.stats.Records-s.matched, s.stats.Errors)
+		if s.stats.Long > 0 {
+			fmt.Fprintf(os.Stderr, ", %d long lines", s.stats.Long)
 		}
-		current = current + 1
-	}
-	if current > 0 {
-		recs = append(recs, line[last:last+current])
+		if verbose && s.found {
+			fmt.Fprintf(os.Stderr, ", time %s to %s", s.firstTime, s.lastTime)
+		}
+		fmt.Fprintln(os.Stderr)
 	}
-	rec.records = recs
-	return nil
+	return ok
 }
 
-func parseTime(delimiter byte, line []byte) (int, time.Time, error) {
-	space := 0
-	for space < len(line) && line[space] != ' ' && line[space] != '\t' && line[space] != delimiter {
-		space = space + 1
-	}
-	date, err := time.Parse(time.RFC3339, string(line[0:space]))
-	if err != nil {
-		return 0, time.Time{}, err
+// join applies the joins to r, false when one of them drops it
+func join(joins []*parsel.Join, r *parsel.Record) bool {
+	for _, j := range joins {
+		if !j.Apply(r) {
+			return false
+		}
 	}
-	return space + 1, date, nil
+	return true
 }
 
-func result(r rec, delimiter string, fields []int, out *bufio.Writer) {
+func result(r parsel.Record, delimiter string, fields []int, out *bufio.Writer) {
 	if len(fields) == 0 {
-		out.WriteString(r.timestamp.Format(time.RFC3339))
-		for _, record := range r.records {
+		out.WriteString(r.Time.Format(time.RFC3339))
+		for _, record := range r.Fields {
 			out.WriteString(delimiter)
 			out.Write(record)
 		}
@@ -247,50 +457,54 @@ func result(r rec, delimiter string, fields []int, out *bufio.Writer) {
 		for _, field := range fields {
 			// field 0 == timestamp
 			fieldIndex := field - 1
-			if fieldIndex < len(r.records) {
+			if fieldIndex < len(r.Fields) {
 				if first {
 					first = false
 				} else {
 					out.WriteString(delimiter)
 				}
 				if fieldIndex == -1 {
-					out.WriteString(r.timestamp.Format(time.RFC3339))
+					out.WriteString(r.Time.Format(time.RFC3339))
 				}
 				if fieldIndex < 0 {
-					negativeRewrite := len(r.records) + fieldIndex + 1
-					if negativeRewrite >= 0 && negativeRewrite < len(r.records) {
-						out.Write(r.records[negativeRewrite])
+					negativeRewrite := len(r.Fields) + fieldIndex + 1
+					if negativeRewrite >= 0 && negativeRewrite < len(r.Fields) {
+						out.Write(r.Fields[negativeRewrite])
 					}
 				} else {
-					out.Write(r.records[fieldIndex])
+					out.Write(r.Fields[fieldIndex])
 				}
 			}
 		}
 	}
+	if len(r.Continuation) > 0 {
+		out.WriteString("\n")
+		out.Write(r.Continuation)
+	}
 	out.WriteString("\n")
 }
 
-func printFieldIndexes(r rec, delimiter string, fields []int, out *bufio.Writer) {
+func printFieldIndexes(r parsel.Record, delimiter string, fields []int, out *bufio.Writer) {
 	if len(fields) == 0 {
-		out.WriteString(fmt.Sprintf("%3d\t%s\n", 0, r.timestamp.Format(time.RFC3339)))
-		for i, rec := range r.records {
-			out.WriteString(fmt.Sprintf("%3d\t%s\n", i+1, rec))
+		out.WriteString(fmt.Sprintf("%3d\t%s\n", 0, r.Time.Format(time.RFC3339)))
+		for i, record := range r.Fields {
+			out.WriteString(fmt.Sprintf("%3d\t%s\n", i+1, record))
 		}
 	} else {
 		for _, field := range fields {
 			fieldIndex := field - 1
-			if fieldIndex < len(r.records) {
+			if fieldIndex < len(r.Fields) {
 				if fieldIndex == -1 {
-					out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.timestamp.Format(time.RFC3339)))
+					out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.Time.Format(time.RFC3339)))
 				} else if fieldIndex < 0 {
-					negativeRewrite := len(r.records) + fieldIndex + 1
-					if negativeRewrite >= 0 && negativeRewrite < len(r.records) {
-						out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.records[negativeRewrite]))
+					negativeRewrite := len(r.Fields) + fieldIndex + 1
+					if negativeRewrite >= 0 && negativeRewrite < len(r.Fields) {
+						out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.Fields[negativeRewrite]))
 					} else {
 						out.WriteString(fmt.Sprintf("%3d\tOut of range\n", field))
 					}
 				} else {
-					out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.records[fieldIndex]))
+					out.WriteString(fmt.Sprintf("%3d\t%s\n", field, r.Fields[fieldIndex]))
 				}
 			} else {
 				out.WriteString(fmt.Sprintf("%3d\tOut of range\n", field))
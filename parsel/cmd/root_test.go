@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParselDefaultDelimiterAnchors(t *testing.T) {
+	file := writeFile(t, "2017-02-13T09:00:00Z\tfoo\tbar\n2017-02-13T09:00:01Z\txfoo\tbarx\n")
+	defer os.Remove(file)
+
+	if res := runParsel(t, []string{"^foo"}, file); res != "2017-02-13T09:00:00Z\tfoo\tbar\n" {
+		t.Errorf("Expected only the line starting with foo but got %q", res)
+	}
+	if res := runParsel(t, []string{"bar$"}, file); res != "2017-02-13T09:00:00Z\tfoo\tbar\n" {
+		t.Errorf("Expected only the line ending with bar but got %q", res)
+	}
+}
+
+func TestParselContextPerFile(t *testing.T) {
+	first := writeFile(t, "2017-02-13T09:00:00Z\ta\n2017-02-13T09:00:01Z\tERROR\n")
+	defer os.Remove(first)
+	second := writeFile(t, "2017-02-13T09:00:02Z\tx\n2017-02-13T09:00:03Z\ty\n2017-02-13T09:00:04Z\tERROR\n")
+	defer os.Remove(second)
+
+	res := runParselArgs(t, &Args{Filters: []string{"ERROR"}, Args: []string{first, second}, After: 2})
+	expect := "2017-02-13T09:00:01Z\tERROR\n--\n2017-02-13T09:00:04Z\tERROR\n"
+	if res != expect {
+		t.Errorf("Expected after context within each file %q but got %q", expect, res)
+	}
+	res = runParselArgs(t, &Args{Filters: []string{"ERROR"}, Args: []string{first, second}, Before: 3})
+	expect = "2017-02-13T09:00:00Z\ta\n2017-02-13T09:00:01Z\tERROR\n--\n" +
+		"2017-02-13T09:00:02Z\tx\n2017-02-13T09:00:03Z\ty\n2017-02-13T09:00:04Z\tERROR\n"
+	if res != expect {
+		t.Errorf("Expected before context within each file %q but got %q", expect, res)
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "parsel")
+	if err != nil {
+		t.Fatal("could not create file", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal("could not write file", err)
+	}
+	return f.Name()
+}
+
+// runParsel runs parsel with filters on files, with the defaults of the
+// command line, and returns what it writes to stdout
+func runParsel(t *testing.T, filters []string, files ...string) string {
+	return runParselArgs(t, &Args{Filters: filters, Args: files})
+}
+
+func runParselArgs(t *testing.T, args *Args) string {
+	args.TimeFormat = "rfc3339"
+	args.Input = "text"
+	args.Output = "text"
+	args.LongLines = "truncate"
+	if args.Workers == 0 {
+		args.Workers = 1
+	}
+	out, err := ioutil.TempFile("", "parsel")
+	if err != nil {
+		t.Fatal("could not create file", err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	Parsel(args)
+	os.Stdout = stdout
+
+	res, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal("could not read output", err)
+	}
+	return string(res)
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/template"
+	"github.com/jwiklund/tools/parsel/parsel"
+	"github.com/pkg/errors"
+)
+
+// templateRec is the data a format template is executed with
+type templateRec struct {
+	Time         time.Time
+	Line         string
+	File         string
+	Fields       []string
+	Continuation string
+}
+
+// parseTemplate returns an output writing each record with a text/template
+// format, a newline is added unless the format ends with one. Fields can
+// only be referred to by name when named.
+func parseTemplate(format string, named bool) (outputFn, error) {
+	var current parsel.Record
+	funcs := template.FuncMap{
+		"field": func(field interface{}) (string, error) {
+			ref, err := templateFieldRef(field, named)
+			if err != nil {
+				return "", err
+			}
+			if ref.Time {
+				return current.Time.Format(time.RFC3339), nil
+			}
+			index := ref.Resolve(current)
+			if index < 0 {
+				return "", nil
+			}
+			return string(current.Fields[index]), nil
+		},
+		"fields": func(from, to int) string {
+			first := parsel.FieldRef{Index: from - 1}.Resolve(current)
+			if from < 0 {
+				first = parsel.FieldRef{Index: from}.Resolve(current)
+			}
+			last := parsel.FieldRef{Index: to - 1}.Resolve(current)
+			if to < 0 {
+				last = parsel.FieldRef{Index: to}.Resolve(current)
+			} else if to > len(current.Fields) {
+				last = len(current.Fields) - 1
+			}
+			if first < 0 || last < first {
+				return ""
+			}
+			var res []string
+			for _, record := range current.Fields[first : last+1] {
+				res = append(res, string(record))
+			}
+			return strings.Join(res, " ")
+		},
+		"time": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"unix": func(t time.Time) int64 {
+			return t.Unix()
+		},
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"trim":      strings.TrimSpace,
+		"replace":   func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":      func(sep string, s []string) string { return strings.Join(s, sep) },
+		"pad": func(width int, s string) string {
+			return fmt.Sprintf("%-*s", width, s)
+		},
+		"truncate": func(width int, s string) string {
+			if len(s) <= width {
+				return s
+			}
+			return s[0:width]
+		},
+	}
+	t, err := template.New("format").Funcs(funcs).Parse(format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid format %s", format)
+	}
+	newline := !strings.HasSuffix(format, "\n")
+	return func(r parsel.Record, file string, delimiter string, fields []int, out *bufio.Writer) {
+		current = r
+		data := templateRec{
+			Time:         r.Time,
+			Line:         string(r.Line),
+			File:         file,
+			Fields:       make([]string, len(r.Fields)),
+			Continuation: string(r.Continuation),
+		}
+		for i, record := range r.Fields {
+			data.Fields[i] = string(record)
+		}
+		if err := t.Execute(out, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not format line %s: %s\n", r.Line, err)
+			return
+		}
+		if newline {
+			out.WriteString("\n")
+		}
+	}, nil
+}
+
+// templateFieldRef accepts field indexes as numbers or strings and names
+func templateFieldRef(field interface{}, named bool) (parsel.FieldRef, error) {
+	switch f := field.(type) {
+	case int:
+		return parsel.ParseFieldRef(fmt.Sprint(f), named)
+	case string:
+		return parsel.ParseFieldRef(f, named)
+	}
+	return parsel.FieldRef{}, errors.Errorf("invalid field %v", field)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	testTemplate(t, `{{.Time.Format "15:04:05"}} {{field 2}} {{field -1}}`, "a b c", "16:02:04 b c")
+	testTemplate(t, `{{field 0}}|{{field 5}}|{{fields 2 -1}}`, "a b c", "2017-03-01T16:02:04Z||b c")
+	testTemplate(t, `{{field 1 | upper}} {{time "2006" .Time}} {{pad 3 (field 2)}}|`, "a b", "A 2017 b  |")
+	testTemplate(t, `{{replace "-" "_" (field 1)}} {{truncate 2 (field 2)}}{{if contains "x" .Line}} x{{end}}`, "a-b xyz", "a_b xy x")
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	if _, err := parseTemplate("{{field 1", false); err == nil {
+		t.Error("Expected error for unclosed action")
+	}
+	if _, err := parseTemplate("{{nofunc 1}}", false); err == nil {
+		t.Error("Expected error for unknown function")
+	}
+}
+
+func testTemplate(t *testing.T, format, row, expect string) {
+	r := parseRecord(t, "2017-03-01T16:02:04Z "+row)
+	write, err := parseTemplate(format, false)
+	if err != nil {
+		t.Fatal("Invalid format", err)
+	}
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+	write(r, "", " ", nil, buf)
+	buf.Flush()
+	if b.String() != expect+"\n" {
+		t.Errorf("Expected %q but got %q", expect+"\n", b.String())
+	}
+}
@@ -14,13 +14,39 @@ func main() {
 	app := kingpin.New("filter", "Filter logs")
 	app.Flag("from", "Only include items from this time").Short('F').StringVar(&args.From)
 	app.Flag("to", "Only include items until this time").Short('T').StringVar(&args.To)
-	app.Flag("delimiter", "Field delimiter").Default("\t").Short('d').StringVar(&args.Delimiter)
-	app.Flag("fields", "Only return fields (eg 1,2,3-4)").Short('f').StringVar(&args.Fields)
-	app.Flag("filter", "Filtering to perform").StringsVar(&args.Filters)
+	app.Flag("delimiter", "Field delimiter, may be several characters (default tab, comma for csv)").Short('d').StringVar(&args.Delimiter)
+	app.Flag("time-format", "Format of the leading time, a Go layout, a preset (rfc3339, rfc3339nano, syslog, nginx, klog, unix, unix-ms, unix-us, unix-ns) or auto").Default("rfc3339").StringVar(&args.TimeFormat)
+	app.Flag("input", "Input format, text, whitespace, csv, json or logfmt").Short('i').Default("text").StringVar(&args.Input)
+	app.Flag("time-key", "Keys of the time in json and logfmt input (default ts,time)").StringVar(&args.TimeKey)
+	app.Flag("fields", "Only return fields, in the given order (eg 1,2,3-4,6-,-3--1)").Short('f').StringVar(&args.Fields)
+	app.Flag("after-context", "Print this many records after matching records").Short('A').IntVar(&args.After)
+	app.Flag("before-context", "Print this many records before matching records").Short('B').IntVar(&args.Before)
+	app.Flag("context", "Print this many records around matching records").Short('C').IntVar(&args.Context)
+	app.Flag("context-time", "Print records within this time of matching records (eg 5s)").StringVar(&args.ContextTime)
+	app.Flag("format", "Output records with a template, eg '{{.Time.Format \"15:04:05\"}} {{field 3}} {{field -1}}'").StringVar(&args.Format)
+	app.Flag("output", "Output format, text, json, csv, tsv or logfmt").Short('o').Default("text").StringVar(&args.Output)
+	app.Flag("group-by", "Aggregate records grouped by fields (eg 3 or 1,-1)").StringVar(&args.GroupBy)
+	app.Flag("agg", "Aggregates per group, count, sum, avg, min and max of a field (eg count,sum:5,max:-1)").StringVar(&args.Aggregates)
+	app.Flag("bucket", "Count records per time bucket of this size (eg 1m)").StringVar(&args.Bucket)
+	app.Flag("bucket-by", "Split bucket counts by the value of a field").StringVar(&args.BucketBy)
+	app.Flag("filter", "Filtering to perform, filters can be combined with and, or, not and parentheses, quote a filter to match it literally").StringsVar(&args.Filters)
+	app.Flag("filter-file", "Only include lines containing any of the patterns in this file, one per line").StringVar(&args.FilterFile)
+	app.Flag("show-pattern", "Append the pattern from filter-file found in each record as its last field").BoolVar(&args.ShowPattern)
+	app.Flag("join", "Append the columns of a tab separated file to records where a field matches its first column, drop records without a match or with ! keep only them (eg users.tsv:3)").StringsVar(&args.Joins)
 	app.Flag("cpuprofile", "Write cpuprofile to file").StringVar(&args.Cpuprofile)
 	app.Flag("preview", "Preview the result, only return 10 rows").Short('p').BoolVar(&args.Preview)
+	app.Flag("merge", "Merge the files ordered by time").Short('m').BoolVar(&args.Merge)
+	app.Flag("follow", "Keep reading the file as it grows, reopening it when rotated").BoolVar(&args.Follow)
+	app.Flag("tag", "Prefix each line with the file it came from").BoolVar(&args.Tag)
+	app.Flag("multiline", "Join lines without a time to the previous record").BoolVar(&args.Multiline)
+	app.Flag("continuation", "Join lines matching this regular expression to the previous record (eg ^\\s)").StringVar(&args.Continuation)
+	app.Flag("max-line", "Maximum line length (eg 1MB for 1000000 bytes or 1MiB for 1048576), no limit by default").StringVar(&args.MaxLine)
+	app.Flag("long-lines", "Truncate, skip or fail on lines longer than max-line").Default("truncate").StringVar(&args.LongLines)
+	app.Flag("workers", "Parse and filter files in chunks with this many workers").Short('w').Default("1").IntVar(&args.Workers)
+	app.Flag("strict", "Fail on the first line that can not be parsed").BoolVar(&args.Strict)
+	app.Flag("quiet-errors", "Do not report lines that can not be parsed, only count them").BoolVar(&args.QuietErrors)
 	app.Flag("verbose", "Be verbose").Short('v').BoolVar(&args.Verbose)
-	app.Arg("files", "Files to read (stdin for stdin)").Required().StringsVar(&args.Args)
+	app.Arg("files", "Files to read (stdin for stdin), gzip, bzip2 and zstd files are decompressed, zstd with the zstd command").Required().StringsVar(&args.Args)
 	app.HelpFlag.Short('h')
 
 	kingpin.MustParse(app.Parse(os.Args[1:]))
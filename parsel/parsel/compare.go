@@ -0,0 +1,89 @@
+package parsel
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/alecthomas/units"
+)
+
+// compareOperators are the field comparisons, longest first
+var compareOperators = []string{">=", "<=", "<", ">", "="}
+
+// parseComparison returns how field values are compared to value and a
+// function comparing a field to it, false for fields of another kind. Values
+// are compared as integers, decimal numbers, durations (eg 200ms) or byte
+// sizes (eg 10MiB or 2.5MB) when value is one, and as bytes otherwise.
+func parseComparison(value string) (string, func([]byte) (int, bool)) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return "number", func(bs []byte) (int, bool) {
+			if v, err := strconv.ParseInt(string(bs), 10, 64); err == nil {
+				return compareInt(v, n), true
+			}
+			v, err := strconv.ParseFloat(string(bs), 64)
+			if err != nil {
+				return 0, false
+			}
+			return compareFloat(v, float64(n)), true
+		}
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return "number", func(bs []byte) (int, bool) {
+			v, err := strconv.ParseFloat(string(bs), 64)
+			if err != nil {
+				return 0, false
+			}
+			return compareFloat(v, n), true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return "duration", func(bs []byte) (int, bool) {
+			v, err := time.ParseDuration(string(bs))
+			if err != nil {
+				return 0, false
+			}
+			return compareInt(int64(v), int64(d)), true
+		}
+	}
+	if n, err := units.ParseStrictBytes(value); err == nil {
+		return "byte size", func(bs []byte) (int, bool) {
+			v, err := ParseByteSize(string(bs))
+			if err != nil {
+				return 0, false
+			}
+			return compareInt(v, n), true
+		}
+	}
+	filterBytes := []byte(value)
+	return "bytes", func(bs []byte) (int, bool) {
+		return bytes.Compare(bs, filterBytes), true
+	}
+}
+
+// ParseByteSize parses a byte size, plain numbers are bytes, KB is 1000
+// bytes and KiB 1024 bytes
+func ParseByteSize(value string) (int64, error) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	return units.ParseStrictBytes(value)
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
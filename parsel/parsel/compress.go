@@ -0,0 +1,174 @@
+package parsel
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type compression int
+
+const (
+	uncompressed compression = iota
+	compressedGzip
+	compressedBzip2
+	compressedZstd
+)
+
+var magics = []struct {
+	magic       []byte
+	compression compression
+}{
+	{[]byte{0x1f, 0x8b}, compressedGzip},
+	{[]byte("BZh"), compressedBzip2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, compressedZstd},
+}
+
+var extensions = map[string]compression{
+	".gz":   compressedGzip,
+	".bz2":  compressedBzip2,
+	".zst":  compressedZstd,
+	".zstd": compressedZstd,
+}
+
+// longest magic, enough bytes to detect any compression
+const magicSize = 4
+
+func (c compression) String() string {
+	switch c {
+	case compressedGzip:
+		return "gzip"
+	case compressedBzip2:
+		return "bzip2"
+	case compressedZstd:
+		return "zstd"
+	}
+	return "uncompressed"
+}
+
+// detectCompression detects compression from the leading bytes of the input,
+// falling back to the file extension
+func detectCompression(head []byte, name string) compression {
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.compression
+		}
+	}
+	return extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// peekCompression detects compression of a non seekable input, the returned
+// reader must be used in place of r
+func peekCompression(r io.Reader, name string) (io.Reader, compression) {
+	in := bufio.NewReader(r)
+	head, _ := in.Peek(magicSize)
+	return in, detectCompression(head, name)
+}
+
+func decompress(r io.Reader, c compression) (io.ReadCloser, error) {
+	switch c {
+	case compressedGzip:
+		return gzip.NewReader(r)
+	case compressedBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case compressedZstd:
+		return decompressCommand(r, "zstd", "-dc")
+	}
+	return io.NopCloser(r), nil
+}
+
+// there is no zstd decoder in the standard library, use the zstd command
+func decompressCommand(r io.Reader, name string, args ...string) (io.ReadCloser, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = r
+	stderr := &headBuffer{max: maxCommandError}
+	cmd.Stderr = stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start %s: %s", name, err)
+	}
+	return &commandReader{ReadCloser: out, cmd: cmd, stderr: stderr}, nil
+}
+
+// most bytes of the error output of a command kept for its error
+const maxCommandError = 1024
+
+// headBuffer keeps the first max bytes written to it
+type headBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if left := h.max - h.Len(); left > 0 {
+		if len(p) > left {
+			h.Buffer.Write(p[0:left])
+		} else {
+			h.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// commandReader reads the output of a command, a failing command is reported
+// at the end of the output instead of io.EOF
+type commandReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *headBuffer
+	waited bool
+	err    error
+}
+
+func (c *commandReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err == io.EOF && c.wait() != nil {
+		return n, c.err
+	}
+	return n, err
+}
+
+func (c *commandReader) wait() error {
+	if !c.waited {
+		c.waited = true
+		if err := c.cmd.Wait(); err != nil {
+			c.err = fmt.Errorf("%s: %s", c.cmd.Args[0], err)
+			if message := strings.TrimSpace(c.stderr.String()); message != "" {
+				c.err = fmt.Errorf("%s: %s: %s", c.cmd.Args[0], err, message)
+			}
+		}
+	}
+	return c.err
+}
+
+// Close ignores failures of a command that did not finish, closing its output
+// early breaks its pipe
+func (c *commandReader) Close() error {
+	c.ReadCloser.Close()
+	if c.waited {
+		return c.err
+	}
+	c.wait()
+	return nil
+}
+
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,125 @@
+package parsel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// "2017-02-13T09:00:00Z\tfirst\n" compressed with bzip2
+var bzip2Line = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xbd, 0x7d,
+	0x9c, 0x0e, 0x00, 0x00, 0x07, 0x5b, 0x80, 0x00, 0x30, 0x00, 0x02, 0x78,
+	0xb0, 0x04, 0x10, 0x01, 0x20, 0x1c, 0x00, 0x20, 0x00, 0x31, 0x43, 0x4d,
+	0x30, 0x00, 0x35, 0x3d, 0x20, 0x34, 0xda, 0x86, 0x91, 0x2f, 0xa8, 0x07,
+	0x29, 0xc3, 0x10, 0xfb, 0x0c, 0x8a, 0x2b, 0x9d, 0xf4, 0xfc, 0x5d, 0xc9,
+	0x14, 0xe1, 0x42, 0x42, 0xf5, 0xf6, 0x70, 0x38,
+}
+
+// "2017-02-13T09:00:00Z\tfirst\n" compressed with zstd
+var zstdLine = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0xd9, 0x00, 0x00, 0x32, 0x30, 0x31,
+	0x37, 0x2d, 0x30, 0x32, 0x2d, 0x31, 0x33, 0x54, 0x30, 0x39, 0x3a, 0x30,
+	0x30, 0x3a, 0x30, 0x30, 0x5a, 0x09, 0x66, 0x69, 0x72, 0x73, 0x74, 0x0a,
+	0xb5, 0x4f, 0xc9, 0xf2,
+}
+
+func TestDetectCompression(t *testing.T) {
+	if c := detectCompression([]byte{0x1f, 0x8b, 0, 0}, "app.log"); c != compressedGzip {
+		t.Error("Expected gzip but got", c)
+	}
+	if c := detectCompression(zstdLine, "app.log"); c != compressedZstd {
+		t.Error("Expected zstd but got", c)
+	}
+	if c := detectCompression([]byte("2017"), "app.log.1.bz2"); c != compressedBzip2 {
+		t.Error("Expected bzip2 but got", c)
+	}
+	if c := detectCompression([]byte("2017"), "app.log"); c != uncompressed {
+		t.Error("Expected uncompressed but got", c)
+	}
+}
+
+func TestReadGzip(t *testing.T) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte("2017-02-13T09:00:00Z\tfirst\n"))
+	w.Close()
+	testReadCompressed(t, "app.log.gz", b.Bytes())
+}
+
+func TestReadBzip2(t *testing.T) {
+	testReadCompressed(t, "app.log", bzip2Line)
+}
+
+func TestReadZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd command not available")
+	}
+	testReadCompressed(t, "app.log", zstdLine)
+}
+
+func TestReadZstdTruncated(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd command not available")
+	}
+	dir, err := ioutil.TempDir("", "parsel")
+	if err != nil {
+		t.Fatal("could not create dir", err)
+	}
+	defer os.RemoveAll(dir)
+	file := dir + "/app.log.zst"
+	if err := ioutil.WriteFile(file, zstdLine[0:len(zstdLine)-8], 0644); err != nil {
+		t.Fatal("could not write file", err)
+	}
+
+	r, err := newReaderFile(file, delimited('\t'), nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	readAllFields(r)
+	if r.Err() == nil || !strings.Contains(r.Err().Error(), "premature end") {
+		t.Error("Expected error with the zstd message for a truncated file but got", r.Err())
+	}
+}
+
+func TestHeadBuffer(t *testing.T) {
+	b := &headBuffer{max: 4}
+	for _, w := range []string{"ab", "cde", "f"} {
+		if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+			t.Error("Expected all bytes to be written but got", n, err)
+		}
+	}
+	if b.String() != "abcd" {
+		t.Error("Expected abcd but got", b.String())
+	}
+}
+
+func testReadCompressed(t *testing.T, name string, content []byte) {
+	dir, err := ioutil.TempDir("", "parsel")
+	if err != nil {
+		t.Fatal("could not create dir", err)
+	}
+	defer os.RemoveAll(dir)
+	file := dir + "/" + name
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		t.Fatal("could not write file", err)
+	}
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T08:00:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+
+	res := readAllFields(r)
+	if res != "first" {
+		t.Error("Expected first but got", res)
+	}
+}
@@ -0,0 +1,313 @@
+package parsel
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// parseExpression parses a filter expression combining filters with and, or,
+// not and parentheses. Adjacent words are joined to a single filter, quote a
+// filter to match it literally. A filter without and, or, a leading not or
+// quotes is a single filter kept as is, parentheses included. Field names
+// are only valid for named input.
+func parseExpression(trace io.Writer, delimiter string, named bool, expression string) (*Filter, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if !isExpression(tokens) {
+		tokens = []token{{tokenFilter, expression}}
+	}
+	p := expressionParser{trace: trace, delimiter: delimiter, named: named, tokens: tokens}
+	fn, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.Errorf("unexpected %s in filter %s", p.tokens[p.pos].value, expression)
+	}
+	return &Filter{match: fn, within: p.within}, nil
+}
+
+func (f filterFn) or(s filterFn) filterFn {
+	return func(r Record) bool {
+		return f(r) || s(r)
+	}
+}
+
+func (f filterFn) not() filterFn {
+	return func(r Record) bool {
+		return !f(r)
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenFilter tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+	tokenQuoted
+)
+
+var keywords = map[string]tokenKind{
+	"and": tokenAnd,
+	"or":  tokenOr,
+	"not": tokenNot,
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenizeExpression(expression string) ([]token, error) {
+	var tokens []token
+	// the filter being read spans from start to end of expression
+	start, end := -1, -1
+	endFilter := func() {
+		if start >= 0 {
+			tokens = append(tokens, token{tokenFilter, expression[start:end]})
+			start = -1
+		}
+	}
+	pos := 0
+	for pos < len(expression) {
+		if unicode.IsSpace(rune(expression[pos])) {
+			pos = pos + 1
+			continue
+		}
+		if expression[pos] == '"' {
+			value, end, err := unquote(expression, pos)
+			if err != nil {
+				return nil, err
+			}
+			endFilter()
+			tokens = append(tokens, token{tokenQuoted, value})
+			pos = end
+			continue
+		}
+		if expression[pos] == '(' {
+			endFilter()
+			tokens = append(tokens, token{tokenOpen, "("})
+			pos = pos + 1
+			continue
+		}
+		if expression[pos] == ')' {
+			endFilter()
+			tokens = append(tokens, token{tokenClose, ")"})
+			pos = pos + 1
+			continue
+		}
+		if end := regexpEnd(expression, pos); end > 0 {
+			endFilter()
+			tokens = append(tokens, token{tokenFilter, expression[pos:end]})
+			pos = end
+			continue
+		}
+		wordStart, wordEnd := pos, pos
+		for wordEnd < len(expression) && !unicode.IsSpace(rune(expression[wordEnd])) {
+			wordEnd = wordEnd + 1
+		}
+		pos = wordEnd
+
+		closing := 0
+		for wordStart < wordEnd && expression[wordEnd-1] == ')' {
+			closing = closing + 1
+			wordEnd = wordEnd - 1
+		}
+		// closing parentheses matching opening ones in the word are part of it,
+		// as in f(x)
+		open := strings.Count(expression[wordStart:wordEnd], "(") - strings.Count(expression[wordStart:wordEnd], ")")
+		for ; open > 0 && closing > 0; open-- {
+			closing = closing - 1
+			wordEnd = wordEnd + 1
+		}
+		word := expression[wordStart:wordEnd]
+		if kind, ok := keywords[word]; ok {
+			endFilter()
+			tokens = append(tokens, token{kind, word})
+		} else if word != "" {
+			if start < 0 {
+				start = wordStart
+			}
+			end = wordEnd
+		}
+		if closing > 0 {
+			endFilter()
+		}
+		for ; closing > 0; closing-- {
+			tokens = append(tokens, token{tokenClose, ")"})
+		}
+	}
+	endFilter()
+	return tokens, nil
+}
+
+// isExpression is true if tokens combine, negate or quote filters, other
+// filters are used as they are, including parentheses and spaces
+func isExpression(tokens []token) bool {
+	for i, t := range tokens {
+		switch t.kind {
+		case tokenAnd, tokenOr, tokenQuoted:
+			return true
+		case tokenNot:
+			if i == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var regexpStart = regexp.MustCompile(`^([^\s:()"]+:)?!?~/`)
+
+// regexpEnd returns the end of the regular expression filter starting at pos,
+// regular expressions may contain spaces and parentheses
+func regexpEnd(expression string, pos int) int {
+	match := regexpStart.FindStringIndex(expression[pos:])
+	if match == nil {
+		return -1
+	}
+	for end := pos + match[1]; end < len(expression); end++ {
+		if expression[end] == '\\' {
+			end = end + 1
+		} else if expression[end] == '/' {
+			end = end + 1
+			for end < len(expression) && unicode.IsLetter(rune(expression[end])) {
+				end = end + 1
+			}
+			return end
+		}
+	}
+	return -1
+}
+
+// unquote reads the quoted string starting at pos, returning it and the
+// position after the closing quote
+func unquote(expression string, pos int) (string, int, error) {
+	var value []byte
+	for end := pos + 1; end < len(expression); end++ {
+		switch expression[end] {
+		case '\\':
+			if end+1 < len(expression) {
+				end = end + 1
+			}
+			value = append(value, expression[end])
+		case '"':
+			return string(value), end + 1, nil
+		default:
+			value = append(value, expression[end])
+		}
+	}
+	return "", 0, errors.Errorf("missing closing quote in filter %s", expression)
+}
+
+type expressionParser struct {
+	trace     io.Writer
+	delimiter string
+	named     bool
+	tokens    []token
+	pos       int
+	// within are the filters comparing records to previous records
+	within []*within
+}
+
+func (p *expressionParser) peek(kind tokenKind) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind
+}
+
+func (p *expressionParser) or() (filterFn, error) {
+	fn, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(tokenOr) {
+		p.pos = p.pos + 1
+		s, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		fn = fn.or(s)
+	}
+	return fn, nil
+}
+
+// and checks the filters with within filters after the others, within
+// compares the records matching the rest to each other
+func (p *expressionParser) and() (filterFn, error) {
+	within := len(p.within)
+	fn, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	fnWithin := len(p.within) > within
+	for p.peek(tokenAnd) {
+		p.pos = p.pos + 1
+		within := len(p.within)
+		s, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		if fnWithin && len(p.within) == within {
+			fn = s.and(fn)
+		} else {
+			fn = fn.and(s)
+		}
+		fnWithin = fnWithin || len(p.within) > within
+	}
+	return fn, nil
+}
+
+func (p *expressionParser) not() (filterFn, error) {
+	if !p.peek(tokenNot) {
+		return p.term()
+	}
+	p.pos = p.pos + 1
+	fn, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return fn.not(), nil
+}
+
+func (p *expressionParser) term() (filterFn, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("missing filter at end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos = p.pos + 1
+	switch t.kind {
+	case tokenOpen:
+		fn, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(tokenClose) {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos = p.pos + 1
+		return fn, nil
+	case tokenFilter, tokenQuoted:
+		if t.value == "" {
+			return nil, fmt.Errorf("empty filter")
+		}
+		if t.kind == tokenQuoted {
+			return filterLiteral(p.trace, t.value), nil
+		}
+		fn, w, err := parseFilter(p.trace, p.delimiter, p.named, t.value)
+		if w != nil {
+			p.within = append(p.within, w)
+		}
+		return fn, err
+	}
+	return nil, errors.Errorf("unexpected %s, expected filter", t.value)
+}
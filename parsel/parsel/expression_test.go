@@ -0,0 +1,85 @@
+package parsel
+
+import (
+	"testing"
+)
+
+func TestExpressionSingle(t *testing.T) {
+	testExpression(t, "a line", "a line", true)
+	testExpression(t, "a line", "a  line", false)
+	testExpression(t, "a line", "1:a", true)
+	testExpression(t, "a line", "!b", true)
+	testExpression(t, "a line", "not b", true)
+	testExpression(t, "a line", "not a", false)
+	testExpression(t, "a line", "not 1:a", false)
+	testExpression(t, "it was not found", "was not found", true)
+	testExpression(t, "it was (null)", "(null)", true)
+	testExpression(t, "it was null", "(null)", false)
+	testExpression(t, "call f(x)", "f(x)", true)
+	testExpression(t, "call f(x)", "f(x) and call", true)
+	testExpression(t, "call f(x)", "(f(x) or g(x))", true)
+	testExpression(t, "an ERROR here", " ERROR ", true)
+	testExpression(t, "ERRORS here", " ERROR ", false)
+}
+
+func TestExpressionOr(t *testing.T) {
+	testExpression(t, "ERROR 200", "1:ERROR or 2:>500", true)
+	testExpression(t, "INFO 503", "1:ERROR or 2:>500", true)
+	testExpression(t, "INFO 200", "1:ERROR or 2:>500", false)
+}
+
+func TestExpressionAndNot(t *testing.T) {
+	testExpression(t, "ERROR 200", "1:ERROR and not 2:200", false)
+	testExpression(t, "ERROR 404", "1:ERROR and not 2:200", true)
+	testExpression(t, "ERROR 404", "not not 1:ERROR", true)
+}
+
+func TestExpressionPrecedence(t *testing.T) {
+	testExpression(t, "a x", "a or b and c", true)
+	testExpression(t, "b x", "a or b and c", false)
+	testExpression(t, "b x", "(a or b) and c", false)
+	testExpression(t, "b c", "(a or b) and c", true)
+	testExpression(t, "a b", "((a) and (b))", true)
+}
+
+func TestExpressionQuoted(t *testing.T) {
+	testExpression(t, "not found", `"not found"`, true)
+	testExpression(t, "not here", `"not found" or "(x)"`, false)
+	testExpression(t, "(x)", `"not found" or "(x)"`, true)
+	testExpression(t, "say \"hi\"", `"\"hi\""`, true)
+	testExpression(t, "GET http://host/a", `"http://host"`, true)
+	testExpression(t, "GET http://other/a", `"http://host"`, false)
+	testExpression(t, "a 1:x", `"1:x"`, true)
+}
+
+func TestExpressionRegexp(t *testing.T) {
+	testExpression(t, "a 503", "~/(a|b) 5/ and 2:~/^5\\d\\d$/", true)
+}
+
+func TestExpressionInvalid(t *testing.T) {
+	for _, filter := range []string{"a or", "and a", `"a`, `""`, "not", "(a or b", "a or b)"} {
+		if _, err := parseExpression(debug, " ", false, filter); err == nil {
+			t.Error("Expected error for", filter)
+		}
+	}
+}
+
+func testExpression(t *testing.T, row string, expression string, expect bool) {
+	line := []byte("2017-03-01T16:02:04Z " + row)
+	var r Record
+	if err := parse(' ', nil, line, &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	fn, err := parseExpression(debug, " ", false, expression)
+	if err != nil {
+		t.Fatal("could not parse expression", expression, err)
+	}
+	if fn.Match(r) != expect {
+		t.Error("expected matching", expect, "but got", fn.Match(r), "for expression", expression, "and line", string(line))
+	}
+}
+
+func TestExpressionRegexpGroups(t *testing.T) {
+	testExpression(t, "a b", "(~/(a|c) b/i or 1:x) and 2:~/b/", true)
+	testExpression(t, "a b", "(1:!~/^a\\/?$/ or 1:x)", false)
+}
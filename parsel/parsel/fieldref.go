@@ -0,0 +1,71 @@
+package parsel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FieldRef refers to a field by index, counting from the last field when
+// negative, or by name for inputs with named fields
+type FieldRef struct {
+	// Index is 0 based for positive indexes, -1 is the last field
+	Index int
+	Name  string
+	// Time refers to the time of the record
+	Time bool
+}
+
+// ParseFieldRef parses a 1 based field index, where 0 is the time, or a
+// field name when named
+func ParseFieldRef(field string, named bool) (FieldRef, error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		if field == "" {
+			return FieldRef{}, err
+		}
+		if !named {
+			return FieldRef{}, fmt.Errorf("could not parse field index %s, names need json or logfmt input", field)
+		}
+		return FieldRef{Name: field}, nil
+	}
+	if index == 0 {
+		return FieldRef{Time: true}, nil
+	} else if index > 0 {
+		index = index - 1
+	}
+	return FieldRef{Index: index}, nil
+}
+
+// Resolve returns the index of the field in the fields of r, -1 if r does
+// not have the field
+func (f FieldRef) Resolve(r Record) int {
+	if f.Name != "" {
+		for i, name := range r.Names {
+			if string(name) == f.Name {
+				return i
+			}
+		}
+		return -1
+	}
+	index := f.Index
+	if index < 0 {
+		index = len(r.Fields) + f.Index
+	}
+	if index < 0 || index >= len(r.Fields) {
+		return -1
+	}
+	return index
+}
+
+func (f FieldRef) String() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	if f.Time {
+		return "0"
+	}
+	if f.Index >= 0 {
+		return strconv.Itoa(f.Index + 1)
+	}
+	return strconv.Itoa(f.Index)
+}
@@ -0,0 +1,332 @@
+package parsel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Filter is a compiled filter
+type Filter struct {
+	match filterFn
+	// within filters compare records to the previous matching record
+	within []*within
+}
+
+// Match returns true for matching records
+func (f *Filter) Match(r Record) bool {
+	res := f.match(r)
+	for _, w := range f.within {
+		if w.checked {
+			w.checked = false
+			w.previous = r.Time
+		}
+	}
+	return res
+}
+
+// Reset forgets the records matched before, such as when reading another file
+func (f *Filter) Reset() {
+	for _, w := range f.within {
+		w.previous = time.Time{}
+	}
+}
+
+// Sequential returns true if records must be checked in order, one at a time
+func (f *Filter) Sequential() bool {
+	return len(f.within) > 0
+}
+
+// And returns a filter matching records matching both filters
+func (f *Filter) And(o *Filter) *Filter {
+	return &Filter{match: andWithinLast(f, o), within: append(f.within[:len(f.within):len(f.within)], o.within...)}
+}
+
+// andWithinLast combines filters with and, checking a filter with within
+// filters last
+func andWithinLast(f, o *Filter) filterFn {
+	if len(f.within) > 0 && len(o.within) == 0 {
+		return o.match.and(f.match)
+	}
+	return f.match.and(o.match)
+}
+
+type filterFn func(Record) bool
+
+func (f filterFn) and(s filterFn) filterFn {
+	return func(r Record) bool {
+		return f(r) && s(r)
+	}
+}
+
+// ParseFilters compiles filter expressions to a filter matching records
+// matching all of them, how each record is filtered is written to trace
+// unless nil. Fields can only be referred to by name when named. An empty
+// delimiter is the default delimiter of text input.
+func ParseFilters(trace io.Writer, delimiter string, named bool, filters []string) (*Filter, error) {
+	if delimiter == "" {
+		delimiter = DefaultDelimiter("")
+	}
+	res := &Filter{match: func(_ Record) bool {
+		return true
+	}}
+	for _, filter := range filters {
+		f, err := parseExpression(trace, delimiter, named, filter)
+		if err != nil {
+			return nil, err
+		}
+		res = res.And(f)
+	}
+
+	return res, nil
+}
+
+// parseFilter parses a single filter, within filters are returned as well
+func parseFilter(trace io.Writer, delimiter string, named bool, filter string) (filterFn, *within, error) {
+	if isRegexp(filter) {
+		re, err := compileRegexp(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		return maybeNot(trace, delimiter, filter, func(t io.Writer, d string, f string) filterFn {
+			return filterRegexp(trace, re)
+		}), nil, nil
+	}
+	colon := strings.Index(filter, ":")
+	if colon < 0 {
+		return maybeNot(trace, delimiter, filter, filterContains), nil, nil
+	}
+	if colon == len(filter)-1 {
+		return nil, nil, errors.Errorf("missing filter for field %s", filter)
+	}
+	field, err := ParseFieldRef(filter[0:colon], named)
+	if err != nil {
+		return nil, nil, errors.Errorf("%s, quote the filter to match it literally", err)
+	}
+	fieldFilter := filter[colon+1:]
+	if field.Time {
+		fn, w, err := parseTimeFilter(trace, strings.TrimPrefix(fieldFilter, "!"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return maybeNot(trace, delimiter, fieldFilter, func(t io.Writer, d string, f string) filterFn {
+			return fn
+		}), w, nil
+	}
+	if file := strings.TrimPrefix(fieldFilter, "!"); strings.HasPrefix(file, "@") {
+		file = file[1:]
+		set, err := loadSet(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		return maybeNot(trace, delimiter, fieldFilter, func(t io.Writer, d string, f string) filterFn {
+			return filterSet(trace, field, file, set)
+		}), nil, nil
+	}
+	if isRegexp(fieldFilter) {
+		re, err := compileRegexp(fieldFilter)
+		if err != nil {
+			return nil, nil, err
+		}
+		return maybeNot(trace, delimiter, fieldFilter, func(t io.Writer, d string, f string) filterFn {
+			return filterFieldRegexp(trace, field, re)
+		}), nil, nil
+	}
+	return maybeNot(trace, delimiter, fieldFilter, func(t io.Writer, d string, f string) filterFn {
+		for _, op := range compareOperators {
+			if strings.HasPrefix(f, op) {
+				return filterCompare(trace, field, op, f[len(op):])
+			}
+		}
+		return filterField(trace, field, f)
+	}), nil, nil
+}
+
+func maybeNot(trace io.Writer, delimiter string, filter string, filterCreator func(io.Writer, string, string) filterFn) filterFn {
+	not := false
+	if filter[0] == '!' {
+		not = true
+		filter = filter[1:]
+	}
+	fn := filterCreator(trace, delimiter, filter)
+	if not {
+		return func(r Record) bool {
+			res := !fn(r)
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.not", filter, ":", res)
+			}
+			return res
+		}
+	}
+	return fn
+}
+
+func filterContains(trace io.Writer, delimiter string, filter string) filterFn {
+	if filter[0] == '^' {
+		filter = delimiter + filter[1:]
+	}
+	var original []byte
+	if filter[len(filter)-1] == '$' {
+		original = []byte(filter[0 : len(filter)-1])
+		filter = string(original) + delimiter
+	}
+	find := []byte(filter)
+	return func(r Record) bool {
+		res := bytes.Contains(r.Line, find)
+		if !res && original != nil {
+			if len(r.Line) >= len(original) {
+				res = bytes.Equal(r.Line[len(r.Line)-len(original):], original)
+			}
+		}
+		if trace != nil {
+			fmt.Fprintln(trace, "filter: ", string(filter), res)
+		}
+		return res
+	}
+}
+
+// filterLiteral matches lines containing filter
+func filterLiteral(trace io.Writer, filter string) filterFn {
+	find := []byte(filter)
+	return func(r Record) bool {
+		res := bytes.Contains(r.Line, find)
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.literal:", filter, res)
+		}
+		return res
+	}
+}
+
+// filterCompare compares a field to a value with op, as numbers, durations
+// or byte sizes when the value is one and as bytes otherwise
+func filterCompare(trace io.Writer, field FieldRef, op, filter string) filterFn {
+	kind, compare := parseComparison(filter)
+	if trace != nil {
+		fmt.Fprintln(trace, "filter.compareField", filter, "compared as", kind)
+	}
+	return func(r Record) bool {
+		fieldIndex := field.Resolve(r)
+		if fieldIndex < 0 {
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.compareField:", field, filter, "too few records")
+			}
+			return false
+		}
+		c, ok := compare(r.Fields[fieldIndex])
+		if !ok {
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.compareField:", field, string(r.Fields[fieldIndex]), "not a", kind)
+			}
+			return false
+		}
+		var res bool
+		switch op {
+		case "<":
+			res = c < 0
+		case "<=":
+			res = c <= 0
+		case ">":
+			res = c > 0
+		case ">=":
+			res = c >= 0
+		case "=":
+			res = c == 0
+		}
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.compareField:", field, string(r.Fields[fieldIndex]), op, filter, res)
+		}
+		return res
+	}
+}
+
+func filterField(trace io.Writer, field FieldRef, filter string) filterFn {
+	var compareFn func([]byte) bool
+
+	if filter[0] == '^' {
+		filterBytes := []byte(filter[1:])
+		filterLen := len(filterBytes)
+		compareFn = func(bs []byte) bool {
+			if len(bs) < filterLen {
+				return false
+			}
+			return bytes.Equal(bs[0:filterLen], filterBytes)
+		}
+	} else if filter[len(filter)-1] == '$' {
+		filterBytes := []byte(filter[0 : len(filter)-1])
+		filterLen := len(filterBytes)
+		compareFn = func(bs []byte) bool {
+			if len(bs) < filterLen {
+				return false
+			}
+			return bytes.Equal(bs[len(bs)-filterLen:], filterBytes)
+		}
+	} else {
+		filterBytes := []byte(filter)
+		compareFn = func(bs []byte) bool {
+			return bytes.Contains(bs, filterBytes)
+		}
+	}
+	return filterFieldCompare(trace, field, filter, compareFn)
+}
+
+func filterFieldCompare(trace io.Writer, field FieldRef, filter string, compareFn func([]byte) bool) filterFn {
+	return func(r Record) bool {
+		fieldIndex := field.Resolve(r)
+		if fieldIndex < 0 {
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.field:", field, filter, "too few records")
+			}
+			return false
+		}
+		res := compareFn(r.Fields[fieldIndex])
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.field:", field, filter, "contains", string(r.Fields[fieldIndex]), res)
+		}
+		return res
+	}
+}
+
+// isRegexp checks for a possibly negated regular expression filter, written
+// as ~/expression/ with an optional i flag for case insensitive matching
+func isRegexp(filter string) bool {
+	return strings.HasPrefix(filter, "~/") || strings.HasPrefix(filter, "!~/")
+}
+
+func compileRegexp(filter string) (*regexp.Regexp, error) {
+	expression := strings.TrimPrefix(strings.TrimPrefix(filter, "!"), "~/")
+	end := strings.LastIndex(expression, "/")
+	if end < 0 {
+		return nil, errors.Errorf("missing / after regular expression %s", filter)
+	}
+	flags := expression[end+1:]
+	expression = expression[0:end]
+	if flags == "i" {
+		expression = "(?i)" + expression
+	} else if flags != "" {
+		return nil, errors.Errorf("unknown regular expression flags %s in %s", flags, filter)
+	}
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid regular expression %s", filter)
+	}
+	return re, nil
+}
+
+func filterRegexp(trace io.Writer, re *regexp.Regexp) filterFn {
+	return func(r Record) bool {
+		res := re.Match(r.Line)
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.regexp:", re, res)
+		}
+		return res
+	}
+}
+
+func filterFieldRegexp(trace io.Writer, field FieldRef, re *regexp.Regexp) filterFn {
+	return filterFieldCompare(trace, field, re.String(), re.Match)
+}
@@ -0,0 +1,194 @@
+package parsel
+
+import (
+	"io"
+	"testing"
+)
+
+var debug io.Writer
+
+func TestFilterLine(t *testing.T) {
+	testFilter(t, "a line", "a line", true)
+	testFilter(t, "a line", "a", true)
+	testFilter(t, "a line", "b", false)
+}
+
+func TestFilterField(t *testing.T) {
+	testFilter(t, "a line", "1:a", true)
+	testFilter(t, "a line", "2:a", false)
+	testFilter(t, "a line", "1:line", false)
+	testFilter(t, "a line", "2:line", true)
+	testFilter(t, "a line", "3:out of bounds", false)
+}
+
+func TestFilterFieldLess(t *testing.T) {
+	testFilter(t, "10", "1:<9", false)
+	testFilter(t, "10", "1:<10", false)
+	testFilter(t, "10", "1:<10.1", true)
+	testFilter(t, "10", "1:<11", true)
+	testFilter(t, "b", "1:<a", false)
+	testFilter(t, "b", "1:<b", false)
+	testFilter(t, "b", "1:<c", true)
+	testFilter(t, "10", "20:<10", false)
+}
+
+func TestFilterFieldMore(t *testing.T) {
+	testFilter(t, "10", "1:>9", true)
+	testFilter(t, "10.1", "1:>10", true)
+	testFilter(t, "10", "1:>10", false)
+	testFilter(t, "10", "1:>11", false)
+	testFilter(t, "b", "1:>a", true)
+	testFilter(t, "b", "1:>b", false)
+	testFilter(t, "b", "1:>c", false)
+	testFilter(t, "10", "20:>20", false)
+}
+
+func TestFilterFieldCompare(t *testing.T) {
+	testFilter(t, "10", "1:>=10", true)
+	testFilter(t, "10", "1:>=11", false)
+	testFilter(t, "10", "1:<=10", true)
+	testFilter(t, "10", "1:<=9.5", false)
+	testFilter(t, "10", "1:=10", true)
+	testFilter(t, "10.0", "1:=10", true)
+	testFilter(t, "b", "1:=b", true)
+	testFilter(t, "ba", "1:=b", false)
+	testFilter(t, "b", "1:>=b", true)
+}
+
+func TestFilterFieldCompare64(t *testing.T) {
+	testFilter(t, "9007199254740993", "1:>9007199254740992", true)
+	testFilter(t, "9007199254740993", "1:=9007199254740993", true)
+	testFilter(t, "16777217", "1:>16777216", true)
+}
+
+func TestFilterFieldDuration(t *testing.T) {
+	testFilter(t, "150ms", "1:>200ms", false)
+	testFilter(t, "1.5s", "1:>200ms", true)
+	testFilter(t, "200ms", "1:>=0.2s", true)
+	testFilter(t, "slow", "1:<200ms", false)
+}
+
+func TestFilterFieldByteSize(t *testing.T) {
+	testFilter(t, "10MiB", "1:>=10MiB", true)
+	testFilter(t, "10MB", "1:>=10MiB", false)
+	testFilter(t, "2.5MB", "1:>2MB", true)
+	testFilter(t, "1024", "1:=1KiB", true)
+	testFilter(t, "big", "1:>1KiB", false)
+}
+
+func TestParseByteSize(t *testing.T) {
+	for value, expect := range map[string]int64{"10": 10, "1KB": 1000, "1KiB": 1024, "2MB": 2000000} {
+		if n, err := ParseByteSize(value); err != nil || n != expect {
+			t.Error("Expected", expect, "for", value, "but got", n, err)
+		}
+	}
+}
+
+func TestFilterLast(t *testing.T) {
+	testFilter(t, "0 1", "-1:1", true)
+	testFilter(t, "0 1", "-1:0", false)
+	testFilter(t, "0 1", "-1:<2", true)
+	testFilter(t, "0 1", "-1:<1", false)
+	testFilter(t, "0 1", "-1:>0", true)
+	testFilter(t, "0 1", "-1:>1", false)
+}
+
+func TestFilterContains(t *testing.T) {
+	testFilter(t, "a line", "2:in", true)
+}
+
+func TestFilterStart(t *testing.T) {
+	testFilter(t, "0 13", "^3", false)
+	testFilter(t, "0 13", "^1", true)
+	testFilter(t, "0 13 31", "3:^1", false)
+	testFilter(t, "0 13 31", "2:^1", true)
+}
+
+func TestFilterEnd(t *testing.T) {
+	testFilter(t, "0 12", "1$", false)
+	testFilter(t, "0 12", "2$", true)
+	testFilter(t, "0 12 21", "2:1$", false)
+	testFilter(t, "0 12 21", "3:1$", true)
+}
+
+func TestFilterNot(t *testing.T) {
+	testFilter(t, "a b", "!a", false)
+	testFilter(t, "a b", "!b", false)
+	testFilter(t, "a b", "!c", true)
+
+	testFilter(t, "a b", "1:!a", false)
+	testFilter(t, "a b", "1:!b", true)
+	testFilter(t, "a b", "2:!a", true)
+	testFilter(t, "a b", "2:!b", false)
+
+	testFilter(t, "1", "1:!<2", false)
+	testFilter(t, "1", "1:!<1", true)
+
+	testFilter(t, "1", "1:!>2", true)
+	testFilter(t, "1", "1:!>0", false)
+}
+
+func TestFilterRegexp(t *testing.T) {
+	testFilter(t, "GET /a 503 timeout after 20ms", "~/timeout after \\d+ms/", true)
+	testFilter(t, "GET /a 503 timeout after ms", "~/timeout after \\d+ms/", false)
+	testFilter(t, "GET /a 503", "3:~/^5\\d\\d$/", true)
+	testFilter(t, "GET /a 5030", "3:~/^5\\d\\d$/", false)
+	testFilter(t, "GET /a:b 503", "~/a:b/", true)
+	testFilter(t, "GET /a 503", "1:~/get/", false)
+	testFilter(t, "GET /a 503", "1:~/get/i", true)
+	testFilter(t, "GET /a 503", "-1:~/^5/", true)
+}
+
+func TestFilterRegexpNot(t *testing.T) {
+	testFilter(t, "GET /a 503", "!~/GET/", false)
+	testFilter(t, "GET /a 503", "!~/POST/", true)
+	testFilter(t, "GET /a 503", "3:!~/^5/", false)
+	testFilter(t, "GET /a 503", "3:!~/^2/", true)
+}
+
+func TestFilterRegexpInvalid(t *testing.T) {
+	for _, filter := range []string{"~/(/", "~/a", "1:~/a/x"} {
+		if _, _, err := parseFilter(debug, " ", false, filter); err == nil {
+			t.Error("Expected error for", filter)
+		}
+	}
+}
+
+func TestFilterNameInvalid(t *testing.T) {
+	for _, filter := range []string{"http://host", "level:warn"} {
+		if _, _, err := parseFilter(debug, " ", false, filter); err == nil {
+			t.Error("Expected error for", filter)
+		}
+	}
+}
+
+func TestParseFiltersDefaultDelimiter(t *testing.T) {
+	filter, err := ParseFilters(debug, "", false, []string{"^foo"})
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	for line, expect := range map[string]bool{"2017-03-01T16:02:04Z\tfoo": true, "2017-03-01T16:02:04Z\txfoo": false} {
+		var r Record
+		if err := parse('\t', nil, []byte(line), &r); err != nil {
+			t.Fatal("could not parse line", err)
+		}
+		if filter.Match(r) != expect {
+			t.Error("Expected matching", expect, "for", line)
+		}
+	}
+}
+
+func testFilter(t *testing.T, row string, filter string, expect bool) {
+	line := []byte("2017-03-01T16:02:04Z " + row)
+	var r Record
+	if err := parse(' ', nil, []byte(line), &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	fn, _, err := parseFilter(debug, " ", false, filter)
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	if fn(r) != expect {
+		t.Error("expected matching", expect, "but got", fn(r), "for filter", filter, "and line", string(line))
+	}
+}
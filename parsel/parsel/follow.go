@@ -0,0 +1,114 @@
+package parsel
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+)
+
+// how often a followed file is checked for new lines
+const followInterval = 250 * time.Millisecond
+
+// newReaderFollow opens a reader that keeps reading file as it grows, like
+// tail -F. idle is called before waiting for more lines.
+func newReaderFollow(file string, p parser, format *timeFormat, from, to time.Time, idle func()) (*Reader, error) {
+	r, err := newReaderFile(file, p, format, from, to)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := r.closer.(*os.File)
+	if !ok {
+		// compressed files are not expected to grow
+		return r, nil
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	fl := &follower{
+		name:     file,
+		file:     f,
+		offset:   offset,
+		interval: followInterval,
+		idle:     idle,
+	}
+	r.scanner = bufio.NewScanner(fl)
+	r.limit.configure(r.scanner, &r.stats.Long)
+	r.closer = fl
+	return r, nil
+}
+
+// follower is a reader that waits for more data at the end of the file, it
+// starts over when the file is truncated and reopens it when it is rotated
+type follower struct {
+	name     string
+	file     *os.File
+	offset   int64
+	interval time.Duration
+	idle     func()
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := f.file.Read(p)
+		f.offset = f.offset + int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		changed, err := f.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			continue
+		}
+		if f.idle != nil {
+			f.idle()
+		}
+		time.Sleep(f.interval)
+	}
+}
+
+// reopen starts over if the file has been truncated or replaced
+func (f *follower) reopen() (bool, error) {
+	latest, err := os.Stat(f.name)
+	if err != nil {
+		// rotated away and not yet recreated
+		return false, nil
+	}
+	current, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(current, latest) {
+		// lines written just before the rotation
+		if current.Size() > f.offset {
+			return true, nil
+		}
+		file, err := os.OpenFile(f.name, os.O_RDONLY, 0)
+		if err != nil {
+			return false, nil
+		}
+		f.file.Close()
+		f.file = file
+		f.offset = 0
+		return true, nil
+	}
+	if latest.Size() < f.offset {
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		f.offset = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+func (f *follower) Close() error {
+	return f.file.Close()
+}
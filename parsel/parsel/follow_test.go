@@ -0,0 +1,66 @@
+package parsel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parsel")
+	if err != nil {
+		t.Fatal("could not create dir", err)
+	}
+	defer os.RemoveAll(dir)
+	file := dir + "/app.log"
+	write := func(flag int, line string) {
+		f, err := os.OpenFile(file, flag|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal("could not open file", err)
+		}
+		defer f.Close()
+		f.WriteString(line)
+	}
+
+	write(os.O_TRUNC, "2017-02-13T09:00:00Z\tfirst\n")
+	r, err := newReaderFollow(file, delimited('\t'), nil, time.Time{}, time.Time{}, nil)
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	r.closer.(*follower).interval = time.Millisecond
+
+	expectFollow(t, r, "first")
+
+	write(os.O_APPEND, "2017-02-13T09:00:01Z\tappended\n")
+	expectFollow(t, r, "appended")
+
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal("could not rotate file", err)
+	}
+	write(os.O_TRUNC, "2017-02-13T09:00:02Z\trotated\n")
+	expectFollow(t, r, "rotated")
+
+	write(os.O_TRUNC, "2017-02-13T09:00:03Z\tt\n")
+	expectFollow(t, r, "t")
+}
+
+func expectFollow(t *testing.T, r *Reader, expect string) {
+	done := make(chan string)
+	go func() {
+		if r.Read() {
+			done <- string(r.rec.Fields[0])
+		} else {
+			done <- ""
+		}
+	}()
+	select {
+	case res := <-done:
+		if res != expect {
+			t.Error("Expected", expect, "but got", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for", expect)
+	}
+}
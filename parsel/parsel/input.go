@@ -0,0 +1,356 @@
+package parsel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// parser parses a line into a record
+type parser interface {
+	parse(format *timeFormat, line []byte, rec *Record) error
+}
+
+// delimited lines start with a time followed by fields separated by the
+// delimiter
+type delimited byte
+
+func (d delimited) parse(format *timeFormat, line []byte, rec *Record) error {
+	return parse(byte(d), format, line, rec)
+}
+
+// default keys of the time in named field input
+var defaultTimeKeys = []string{"ts", "time"}
+
+// parseInput parses the input format, timeKeys is a comma separated list of
+// keys used for the time of named field input
+func parseInput(input, delimiter, timeKeys string) (parser, error) {
+	keys := defaultTimeKeys
+	if timeKeys != "" {
+		keys = strings.Split(timeKeys, ",")
+	}
+	switch strings.ToLower(input) {
+	case "", "text":
+		if len(delimiter) == 0 {
+			return nil, fmt.Errorf("empty delimiter not supported")
+		} else if len(delimiter) > 1 {
+			return multiDelimited(delimiter), nil
+		}
+		return delimited(delimiter[0]), nil
+	case "whitespace":
+		return whitespaceDelimited{}, nil
+	case "csv":
+		comma, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || comma == utf8.RuneError {
+			return nil, fmt.Errorf("csv delimiter must be a single character")
+		}
+		return csvInput(comma), nil
+	case "json":
+		return jsonInput{timeKeys: keys}, nil
+	case "logfmt":
+		return logfmtInput{timeKeys: keys}, nil
+	}
+	return nil, fmt.Errorf("unknown input %s (must be text, whitespace, csv, json or logfmt)", input)
+}
+
+// NamedInput is true for inputs with named fields
+func NamedInput(input string) bool {
+	switch strings.ToLower(input) {
+	case "json", "logfmt":
+		return true
+	}
+	return false
+}
+
+// DefaultDelimiter is the delimiter of input and output unless given
+func DefaultDelimiter(input string) string {
+	switch strings.ToLower(input) {
+	case "csv":
+		return ","
+	case "whitespace":
+		return " "
+	}
+	return "\t"
+}
+
+// multiDelimited lines are delimited by a string such as " | "
+type multiDelimited string
+
+func (d multiDelimited) parse(format *timeFormat, line []byte, rec *Record) error {
+	next, t, err := parseTime(string(d), format, line)
+	if err != nil {
+		return err
+	}
+	rec.Time = t
+	rec.Line = line
+	recs := rec.Fields[0:0]
+	delimiter := []byte(d)
+	if bytes.HasPrefix(line[next-1:], delimiter) {
+		next = next - 1 + len(delimiter)
+	}
+	if next <= len(line) {
+		rest := line[next:]
+		for {
+			i := bytes.Index(rest, delimiter)
+			if i < 0 {
+				recs = append(recs, rest)
+				break
+			}
+			recs = append(recs, rest[0:i])
+			rest = rest[i+len(delimiter):]
+		}
+	}
+	rec.Fields = recs
+	return nil
+}
+
+// whitespaceDelimited lines are delimited by runs of spaces and tabs, like
+// awk does
+type whitespaceDelimited struct{}
+
+func (whitespaceDelimited) parse(format *timeFormat, line []byte, rec *Record) error {
+	next, t, err := parseTime(" ", format, line)
+	if err != nil {
+		return err
+	}
+	rec.Time = t
+	rec.Line = line
+	recs := rec.Fields[0:0]
+	for next < len(line) {
+		for next < len(line) && isSpace(line[next]) {
+			next = next + 1
+		}
+		start := next
+		for next < len(line) && !isSpace(line[next]) {
+			next = next + 1
+		}
+		if start < next {
+			recs = append(recs, line[start:next])
+		}
+	}
+	rec.Fields = recs
+	return nil
+}
+
+// csvInput lines are RFC 4180 records with the time in the first column,
+// records can not span lines
+type csvInput rune
+
+func (c csvInput) parse(format *timeFormat, line []byte, rec *Record) error {
+	r := csv.NewReader(bytes.NewReader(line))
+	r.Comma = rune(c)
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err != nil {
+		return err
+	}
+	rec.Time, err = parseTimeValue(format, []byte(fields[0]))
+	if err != nil {
+		return err
+	}
+	rec.Line = line
+	recs := rec.Fields[0:0]
+	for _, field := range fields[1:] {
+		recs = append(recs, []byte(field))
+	}
+	rec.Fields = recs
+	return nil
+}
+
+// namedFields adds named fields to a record, the first field named by a time
+// key is the time of the record
+type namedFields struct {
+	rec      *Record
+	format   *timeFormat
+	timeKeys []string
+	found    bool
+}
+
+func newNamedFields(format *timeFormat, timeKeys []string, line []byte, rec *Record) *namedFields {
+	rec.Line = line
+	rec.Fields = rec.Fields[0:0]
+	rec.Names = rec.Names[0:0]
+	return &namedFields{rec: rec, format: format, timeKeys: timeKeys}
+}
+
+func (n *namedFields) add(name, value []byte) error {
+	if !n.found {
+		for _, key := range n.timeKeys {
+			if string(name) == key {
+				var err error
+				n.rec.Time, err = parseTimeValue(n.format, value)
+				n.found = err == nil
+				return err
+			}
+		}
+	}
+	n.rec.Names = append(n.rec.Names, name)
+	n.rec.Fields = append(n.rec.Fields, value)
+	return nil
+}
+
+func (n *namedFields) done() error {
+	if !n.found {
+		return fmt.Errorf("missing time key %s", strings.Join(n.timeKeys, " or "))
+	}
+	return nil
+}
+
+// jsonInput parses JSON objects, one per line, into fields named by their key
+// path such as http.status
+type jsonInput struct {
+	timeKeys []string
+}
+
+func (j jsonInput) parse(format *timeFormat, line []byte, rec *Record) error {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	t, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if t != json.Delim('{') {
+		return fmt.Errorf("not a json object")
+	}
+	fields := newNamedFields(format, j.timeKeys, line, rec)
+	err = flattenJSON(decoder, "", func(name string, value []byte) error {
+		return fields.add([]byte(name), value)
+	})
+	if err != nil {
+		return err
+	}
+	return fields.done()
+}
+
+// flattenJSON reads the rest of an object calling add with the key path and
+// value of each leaf, array elements are keyed by their index
+func flattenJSON(decoder *json.Decoder, prefix string, add func(string, []byte) error) error {
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("invalid json key %v", t)
+		}
+		if err := flattenJSONValue(decoder, prefix+key, add); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+func flattenJSONValue(decoder *json.Decoder, name string, add func(string, []byte) error) error {
+	t, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch v := t.(type) {
+	case json.Delim:
+		if v == '{' {
+			return flattenJSON(decoder, name+".", add)
+		}
+		for i := 0; decoder.More(); i++ {
+			if err := flattenJSONValue(decoder, name+"."+strconv.Itoa(i), add); err != nil {
+				return err
+			}
+		}
+		_, err := decoder.Token()
+		return err
+	case string:
+		return add(name, []byte(v))
+	case json.Number:
+		return add(name, []byte(v))
+	case bool:
+		return add(name, []byte(strconv.FormatBool(v)))
+	case nil:
+		return add(name, []byte("null"))
+	}
+	return fmt.Errorf("unexpected json %v", t)
+}
+
+// parseTimeValue parses a time that is the whole value
+func parseTimeValue(format *timeFormat, value []byte) (time.Time, error) {
+	if format == nil {
+		format = defaultTimeFormat
+	}
+	end, t, err := format.parse("", value)
+	if err != nil {
+		return t, err
+	}
+	if end < len(value) {
+		return t, fmt.Errorf("unexpected %s after time", value[end:])
+	}
+	return t, nil
+}
+
+// logfmtInput parses key=value pairs, values may be quoted and escaped as Go
+// strings
+type logfmtInput struct {
+	timeKeys []string
+}
+
+func (l logfmtInput) parse(format *timeFormat, line []byte, rec *Record) error {
+	fields := newNamedFields(format, l.timeKeys, line, rec)
+	pos := 0
+	for {
+		for pos < len(line) && isSpace(line[pos]) {
+			pos = pos + 1
+		}
+		if pos == len(line) {
+			break
+		}
+		start := pos
+		for pos < len(line) && line[pos] != '=' && !isSpace(line[pos]) {
+			pos = pos + 1
+		}
+		key := line[start:pos]
+		if len(key) == 0 {
+			return fmt.Errorf("missing key at %d", pos)
+		}
+		var value []byte
+		if pos < len(line) && line[pos] == '=' {
+			pos = pos + 1
+			if pos < len(line) && line[pos] == '"' {
+				end := pos + 1
+				for end < len(line) && line[end] != '"' {
+					if line[end] == '\\' {
+						end = end + 1
+					}
+					end = end + 1
+				}
+				if end >= len(line) {
+					return fmt.Errorf("missing closing quote for %s", key)
+				}
+				quoted := line[pos : end+1]
+				pos = end + 1
+				if unquoted, err := strconv.Unquote(string(quoted)); err == nil {
+					value = []byte(unquoted)
+				} else {
+					value = quoted[1 : len(quoted)-1]
+				}
+			} else {
+				start := pos
+				for pos < len(line) && !isSpace(line[pos]) {
+					pos = pos + 1
+				}
+				value = line[start:pos]
+			}
+		}
+		if err := fields.add(key, value); err != nil {
+			return err
+		}
+	}
+	return fields.done()
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
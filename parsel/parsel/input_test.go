@@ -0,0 +1,164 @@
+package parsel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const jsonLines = `{"time":"2017-02-13T09:00:00Z","level":"info","http":{"status":200,"path":"/a"},"tags":["x","y"]}
+{"level":"error","time":"2017-02-13T10:00:00Z","http":{"status":503},"err":{"msg":"timeout after 20ms"},"ok":false,"v":null}
+not json
+{"level":"error"}`
+
+func TestJSONInput(t *testing.T) {
+	r := jsonReader(t, jsonLines, nil, time.Time{})
+	var res []string
+	for r.Read() {
+		var kvs []string
+		for i, name := range r.rec.Names {
+			kvs = append(kvs, string(name)+"="+string(r.rec.Fields[i]))
+		}
+		res = append(res, r.rec.Time.Format(time.RFC3339)+" "+strings.Join(kvs, " "))
+	}
+	expect := "2017-02-13T09:00:00Z level=info http.status=200 http.path=/a tags.0=x tags.1=y|" +
+		"2017-02-13T10:00:00Z level=error http.status=503 err.msg=timeout after 20ms ok=false v=null"
+	if strings.Join(res, "|") != expect {
+		t.Errorf("Expected %q but got %q", expect, strings.Join(res, "|"))
+	}
+}
+
+func TestJSONInputRange(t *testing.T) {
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T09:30:00Z")
+	r := jsonReader(t, jsonLines, nil, from)
+	count := 0
+	for r.Read() {
+		count = count + 1
+	}
+	if count != 1 {
+		t.Error("Expected 1 record but got", count)
+	}
+}
+
+func TestJSONInputEpoch(t *testing.T) {
+	r := jsonReader(t, `{"time":1486977417.5,"a":1}`, autoTimeFormat, time.Time{})
+	if !r.Read() {
+		t.Fatal("Could not read line")
+	}
+	if r.rec.Time.Format(time.RFC3339Nano) != "2017-02-13T09:16:57.5Z" {
+		t.Error("Expected 2017-02-13T09:16:57.5Z but got", r.rec.Time.Format(time.RFC3339Nano))
+	}
+}
+
+func jsonReader(t *testing.T, content string, format *timeFormat, from time.Time) *Reader {
+	p, err := parseInput("json", "\t", "time")
+	if err != nil {
+		t.Fatal("Invalid input", err)
+	}
+	r, err := newReader(strings.NewReader(content), p, format, from, time.Time{})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	return r
+}
+
+func TestLogfmtInput(t *testing.T) {
+	content := `ts=2017-02-13T09:00:00Z level=warn msg="retry \"db\" in 5s" dur=12ms flag
+time=2017-02-13T10:00:00Z ts=x msg=ok
+level=warn msg="unterminated
+level=warn msg=no-time`
+	p, err := parseInput("logfmt", "\t", "")
+	if err != nil {
+		t.Fatal("Invalid input", err)
+	}
+	r, err := newReader(strings.NewReader(content), p, nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	var res []string
+	for r.Read() {
+		var kvs []string
+		for i, name := range r.rec.Names {
+			kvs = append(kvs, string(name)+"="+string(r.rec.Fields[i]))
+		}
+		res = append(res, r.rec.Time.Format(time.RFC3339)+" "+strings.Join(kvs, " "))
+	}
+	expect := `2017-02-13T09:00:00Z level=warn msg=retry "db" in 5s dur=12ms flag=|` +
+		`2017-02-13T10:00:00Z ts=x msg=ok`
+	if strings.Join(res, "|") != expect {
+		t.Errorf("Expected %q but got %q", expect, strings.Join(res, "|"))
+	}
+}
+
+func TestLogfmtInputFilter(t *testing.T) {
+	p, _ := parseInput("logfmt", "\t", "ts")
+	var r Record
+	if err := p.parse(nil, []byte(`ts=2017-02-13T09:00:00Z level=warn msg="a b"`), &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	filter, err := ParseFilters(debug, "\t", true, []string{`level:warn and msg:^a`})
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	if !filter.Match(r) {
+		t.Error("Expected filter to match")
+	}
+}
+
+func TestDelimitedInputs(t *testing.T) {
+	testInput(t, "text", " | ", "2017-02-13T09:00:00Z | a | b c | ", "a,b c,")
+	testInput(t, "text", "||", "2017-02-13T09:00:00Z||a||b", "a,b")
+	testInput(t, "text", "::", "2017-02-13T09:00:00Z::a::b", "a,b")
+	testInput(t, "text", "-|", "2017-02-13T09:00:00Z-|a-|b", "a,b")
+	testInput(t, "text", "0;", "2017-02-13T09:00:00Z0;a", "a")
+	testInput(t, "text", " | ", "2017-02-13T09:00:00Z", "")
+	testInput(t, "whitespace", " ", "2017-02-13T09:00:00Z   a \t b  ", "a,b")
+	testInput(t, "csv", ",", `"2017-02-13T09:00:00Z",a,"b,c","say ""hi"""`, `a,b,c,say "hi"`)
+	testInput(t, "csv", ";", `2017-02-13T09:00:00Z;"a;b";`, "a;b,")
+}
+
+func TestDelimitedInputFilters(t *testing.T) {
+	p, _ := parseInput("csv", ",", "")
+	var r Record
+	if err := p.parse(nil, []byte(`2017-02-13T09:00:00Z,GET,"/a,b",503`), &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	filter, err := ParseFilters(debug, ",", false, []string{"2:/a,b$ and -1:<500"})
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	if filter.Match(r) {
+		t.Error("Expected filter not to match")
+	}
+	filter, _ = ParseFilters(debug, ",", false, []string{"2:^/a,b and -1:>500"})
+	if !filter.Match(r) {
+		t.Error("Expected filter to match")
+	}
+}
+
+func TestInputInvalid(t *testing.T) {
+	if _, err := parseInput("csv", "ab", ""); err == nil {
+		t.Error("Expected error for multi character csv delimiter")
+	}
+	if _, err := parseInput("text", "", ""); err == nil {
+		t.Error("Expected error for empty delimiter")
+	}
+}
+
+func testInput(t *testing.T, input, delimiter, line, expect string) {
+	p, err := parseInput(input, delimiter, "")
+	if err != nil {
+		t.Fatal("Invalid input", err)
+	}
+	var r Record
+	if err := p.parse(nil, []byte(line), &r); err != nil {
+		t.Fatal("could not parse line", line, err)
+	}
+	var fields []string
+	for _, record := range r.Fields {
+		fields = append(fields, string(record))
+	}
+	if strings.Join(fields, ",") != expect {
+		t.Errorf("Expected %q but got %q for %s", expect, strings.Join(fields, ","), line)
+	}
+}
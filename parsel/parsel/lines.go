@@ -0,0 +1,78 @@
+package parsel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// what to do with lines longer than the maximum line length
+const (
+	truncateLongLines = "truncate"
+	skipLongLines     = "skip"
+	failLongLines     = "fail"
+)
+
+// lineLimit is the maximum length of lines and what to do with longer lines,
+// a max of 0 is no limit
+type lineLimit struct {
+	max  int
+	long string
+}
+
+func parseLineLimit(max int, long string) (lineLimit, error) {
+	if max < 0 {
+		return lineLimit{}, fmt.Errorf("invalid max line length %d", max)
+	}
+	switch strings.ToLower(long) {
+	case "", truncateLongLines:
+		return lineLimit{max, truncateLongLines}, nil
+	case skipLongLines, failLongLines:
+		return lineLimit{max, strings.ToLower(long)}, nil
+	}
+	return lineLimit{}, fmt.Errorf("unknown long lines %s (must be truncate, skip or fail)", long)
+}
+
+// configure makes scanner read lines up to the limit, long is incremented
+// for each line truncated or skipped. It must be called before scanning.
+func (l lineLimit) configure(scanner *bufio.Scanner, long *int) {
+	if l.max == 0 {
+		scanner.Buffer(nil, int(^uint(0)>>1))
+		return
+	}
+	scanner.Buffer(nil, l.max+1)
+	scanner.Split(l.split(long))
+}
+
+// split splits lines like bufio.ScanLines, lines longer than the limit are
+// truncated or skipped, when skipped an empty line is returned in their place
+func (l lineLimit) split(long *int) bufio.SplitFunc {
+	discard := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if discard {
+			newline := bytes.IndexByte(data, '\n')
+			if newline < 0 {
+				return len(data), nil, nil
+			}
+			discard = false
+			return newline + 1, nil, nil
+		}
+		newline := bytes.IndexByte(data, '\n')
+		if (newline >= 0 && newline <= l.max) || (newline < 0 && atEOF && len(data) <= l.max) {
+			return bufio.ScanLines(data, atEOF)
+		}
+		if newline < 0 && len(data) <= l.max {
+			return 0, nil, nil
+		}
+		if l.long == failLongLines {
+			return 0, nil, fmt.Errorf("line longer than %d bytes", l.max)
+		}
+		*long = *long + 1
+		discard = true
+		if l.long == truncateLongLines {
+			return l.max, data[0:l.max], nil
+		}
+		return l.max, data[0:0], nil
+	}
+}
@@ -0,0 +1,96 @@
+package parsel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+var longLines = []string{
+	"2017-02-13T10:00:00Z\tshort",
+	"2017-02-13T10:00:01Z\t" + strings.Repeat("x", 100*1024),
+	"broken",
+	"2017-02-13T10:00:02Z\tlast",
+}
+
+func TestReadLongLines(t *testing.T) {
+	testLongLines(t, Options{}, "short,"+strings.Repeat("x", 100*1024)+",last", "3:broken", "")
+	testLongLines(t, Options{MaxLine: 30}, "short,xxxxxxxxx,last", "3:broken", "")
+	testLongLines(t, Options{MaxLine: 30, LongLines: "skip"}, "short,last", "3:broken", "")
+	testLongLines(t, Options{MaxLine: 30, LongLines: "fail"}, "short", "", "line 2: line longer than 30 bytes")
+}
+
+func TestReadLongLinesParallel(t *testing.T) {
+	file := writeLog(t, append(append(append([]string{}, longLines...), longLines...), longLines...))
+	defer os.Remove(file)
+	defer func(size int64) {
+		chunkSize = size
+	}(chunkSize)
+	chunkSize = 1024
+
+	for _, long := range []string{"truncate", "skip", "fail"} {
+		var errors []string
+		r, err := Open(file, Options{MaxLine: 30, LongLines: long, ParseError: func(e *LineError) error {
+			errors = append(errors, e.Error())
+			return nil
+		}})
+		if err != nil {
+			t.Fatal("Could not open", err)
+		}
+		count := 0
+		err = r.ReadParallel(4, &Filter{match: func(Record) bool { return true }}, func(Record) bool {
+			count = count + 1
+			return true
+		})
+		r.Close()
+		switch long {
+		case "truncate":
+			if err != nil || count != 9 || len(errors) != 3 || r.Stats().Long != 3 {
+				t.Error("Expected 9 records, 3 errors and 3 long lines but got", count, errors, r.Stats(), err)
+			}
+		case "skip":
+			if err != nil || count != 6 || len(errors) != 3 || r.Stats().Long != 3 {
+				t.Error("Expected 6 records, 3 errors and 3 long lines but got", count, errors, r.Stats(), err)
+			}
+		case "fail":
+			if err == nil || err.Error() != "line 2: line longer than 30 bytes" || count != 1 {
+				t.Error("Expected error on line 2 after 1 record but got", count, err)
+			}
+		}
+	}
+}
+
+func TestLineLimitInvalid(t *testing.T) {
+	if err := (Options{MaxLine: 10, LongLines: "wrap"}).Validate(); err == nil {
+		t.Error("Expected error for wrap")
+	}
+	if err := (Options{MaxLine: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative max line")
+	}
+}
+
+func testLongLines(t *testing.T, o Options, expect, expectErrors, expectErr string) {
+	var errors []string
+	o.ParseError = func(e *LineError) error {
+		errors = append(errors, fmt.Sprintf("%d:%s", e.Line, e.Text))
+		return nil
+	}
+	r, err := NewReader(strings.NewReader(strings.Join(longLines, "\n")), o)
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	var res []string
+	for r.Read() {
+		res = append(res, string(r.Record().Fields[0]))
+	}
+	if strings.Join(res, ",") != expect {
+		t.Errorf("Expected %.40q but got %.40q for %+v", expect, strings.Join(res, ","), o)
+	}
+	if strings.Join(errors, ",") != expectErrors {
+		t.Errorf("Expected errors %s but got %s for %+v", expectErrors, strings.Join(errors, ","), o)
+	}
+	if (r.Err() == nil && expectErr != "") || (r.Err() != nil && r.Err().Error() != expectErr) {
+		t.Errorf("Expected error %q but got %v for %+v", expectErr, r.Err(), o)
+	}
+}
@@ -0,0 +1,142 @@
+package parsel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// readLookup calls fn with the non empty lines of a lookup file
+func readLookup(file string, fn func(line []byte)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLookupLine)
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		if len(bytes.TrimSpace(line)) > 0 {
+			fn(line)
+		}
+	}
+	return scanner.Err()
+}
+
+// longest line of a lookup file
+const maxLookupLine = 1024 * 1024
+
+// loadSet reads the values of a set file, one per line
+func loadSet(file string) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	err := readLookup(file, func(line []byte) {
+		set[string(bytes.TrimSpace(line))] = struct{}{}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read set %s", file)
+	}
+	return set, nil
+}
+
+// filterSet matches records where field is one of the values in set
+func filterSet(trace io.Writer, field FieldRef, file string, set map[string]struct{}) filterFn {
+	return func(r Record) bool {
+		fieldIndex := field.Resolve(r)
+		if fieldIndex < 0 {
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.set:", field, file, "too few records")
+			}
+			return false
+		}
+		_, res := set[string(r.Fields[fieldIndex])]
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.set:", field, string(r.Fields[fieldIndex]), "in", file, res)
+		}
+		return res
+	}
+}
+
+// Join appends the columns of a tab separated lookup table to records where
+// a field matches the first column of the table
+type Join struct {
+	field FieldRef
+	rows  map[string][][]byte
+	names [][]byte
+	// not keeps the records without a match instead
+	not bool
+}
+
+// ParseJoin parses file:field, joining records by field with the rows of
+// file. Records without a matching row are dropped, or with a leading ! only
+// records without a matching row are kept. The field can only be a name when
+// named.
+func ParseJoin(join string, named bool) (*Join, error) {
+	j := &Join{rows: make(map[string][][]byte)}
+	spec := join
+	if strings.HasPrefix(spec, "!") {
+		j.not = true
+		spec = spec[1:]
+	}
+	colon := strings.LastIndex(spec, ":")
+	if colon <= 0 || colon == len(spec)-1 {
+		return nil, errors.Errorf("invalid join %s (must be file:field)", join)
+	}
+	file := spec[0:colon]
+	field, err := ParseFieldRef(spec[colon+1:], named)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid join %s", join)
+	}
+	if field.Time {
+		return nil, errors.Errorf("invalid join field %s", spec[colon+1:])
+	}
+	j.field = field
+	columns := 0
+	err = readLookup(file, func(line []byte) {
+		row := bytes.Split(line, []byte("\t"))
+		if _, ok := j.rows[string(row[0])]; ok {
+			return
+		}
+		j.rows[string(row[0])] = row[1:]
+		if len(row)-1 > columns {
+			columns = len(row) - 1
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read join %s", file)
+	}
+	// joined columns of named records are named by the table and column
+	table := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	for i := 0; i < columns; i++ {
+		j.names = append(j.names, []byte(table+"."+strconv.Itoa(i+2)))
+	}
+	return j, nil
+}
+
+// Apply appends the columns of the row matching r to its fields, it returns
+// false for records the join drops
+func (j *Join) Apply(r *Record) bool {
+	fieldIndex := j.field.Resolve(*r)
+	var row [][]byte
+	ok := false
+	if fieldIndex >= 0 {
+		row, ok = j.rows[string(r.Fields[fieldIndex])]
+	}
+	if j.not || !ok {
+		return j.not && !ok
+	}
+	named := r.Names != nil && len(r.Names) == len(r.Fields)
+	// the fields may share their backing array with the reader
+	r.Fields = append(r.Fields[:len(r.Fields):len(r.Fields)], row...)
+	if named {
+		r.Names = append(r.Names[:len(r.Names):len(r.Names)], j.names[:len(row)]...)
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package parsel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilterSet(t *testing.T) {
+	file := writeLog(t, []string{"u1", " u3 ", "", "u7"})
+	defer os.Remove(file)
+
+	testFilter(t, "GET u3", "2:@"+file, true)
+	testFilter(t, "GET u2", "2:@"+file, false)
+	testFilter(t, "GET u2", "2:!@"+file, true)
+	testFilter(t, "GET u7", "2:!@"+file, false)
+	testFilter(t, "GET", "2:@"+file, false)
+	if _, _, err := parseFilter(debug, " ", false, "2:@"+file+".missing"); err == nil {
+		t.Error("Expected error for missing set file")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	file := writeLog(t, []string{"u1\talice\tadmin", "u2\tbob", "u1\tduplicate"})
+	defer os.Remove(file)
+
+	j, err := ParseJoin(file+":2", false)
+	if err != nil {
+		t.Fatal("could not parse join", err)
+	}
+	r := parseJoinRecord(t, "2017-03-01T16:02:04Z GET u1")
+	if !j.Apply(&r) || joinedFields(r) != "GET u1 alice admin" {
+		t.Error("Expected u1 to be joined with alice admin but got", joinedFields(r))
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z GET u2")
+	if !j.Apply(&r) || joinedFields(r) != "GET u2 bob" {
+		t.Error("Expected u2 to be joined with bob but got", joinedFields(r))
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z GET u3")
+	if j.Apply(&r) {
+		t.Error("Expected u3 without a match to be dropped")
+	}
+
+	not, err := ParseJoin("!"+file+":2", false)
+	if err != nil {
+		t.Fatal("could not parse join", err)
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z GET u3")
+	if !not.Apply(&r) || joinedFields(r) != "GET u3" {
+		t.Error("Expected u3 to be kept unchanged but got", joinedFields(r))
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z GET u1")
+	if not.Apply(&r) {
+		t.Error("Expected u1 with a match to be dropped")
+	}
+}
+
+func TestJoinNamed(t *testing.T) {
+	file := writeLog(t, []string{"u1\talice"})
+	defer os.Remove(file)
+
+	j, err := ParseJoin(file+":user", true)
+	if err != nil {
+		t.Fatal("could not parse join", err)
+	}
+	r := Record{Fields: [][]byte{[]byte("u1")}, Names: [][]byte{[]byte("user")}}
+	if !j.Apply(&r) {
+		t.Fatal("Expected user u1 to be joined")
+	}
+	index := FieldRef{Name: filepath.Base(file) + ".2"}.Resolve(r)
+	if index != 1 {
+		t.Error("Expected the joined column to be named by the table but got", string(r.Names[len(r.Names)-1]))
+	}
+}
+
+func TestJoinInvalid(t *testing.T) {
+	for _, join := range []string{"users.tsv", "users.tsv:", ":3", "users.tsv:0", "missing.tsv:1", "users.tsv:user"} {
+		if _, err := ParseJoin(join, false); err == nil {
+			t.Error("Expected error for", join)
+		}
+	}
+}
+
+func parseJoinRecord(t *testing.T, line string) Record {
+	var r Record
+	if err := parse(' ', nil, []byte(line), &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	return r
+}
+
+func joinedFields(r Record) string {
+	var fields []string
+	for _, f := range r.Fields {
+		fields = append(fields, string(f))
+	}
+	return strings.Join(fields, " ")
+}
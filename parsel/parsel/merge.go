@@ -0,0 +1,100 @@
+package parsel
+
+import (
+	"container/heap"
+)
+
+// MergeReader reads records from several readers ordered by time, records
+// with equal time are read in reader order
+type MergeReader struct {
+	readers []*Reader
+	heap    readerHeap
+	started bool
+	current int
+	rec     Record
+	err     error
+}
+
+func NewMergeReader(readers []*Reader) *MergeReader {
+	return &MergeReader{readers: readers, current: -1}
+}
+
+func (m *MergeReader) Read() bool {
+	if m.err != nil {
+		return false
+	}
+	if !m.started {
+		m.started = true
+		for i, r := range m.readers {
+			if r.Read() {
+				m.heap = append(m.heap, readerIndex{r, i})
+			} else if m.err = r.Err(); m.err != nil {
+				m.current = i
+				return false
+			}
+		}
+		heap.Init(&m.heap)
+	} else if m.current >= 0 {
+		// the previous record is only valid until its reader is advanced
+		if m.heap[0].reader.Read() {
+			heap.Fix(&m.heap, 0)
+		} else if m.err = m.heap[0].reader.Err(); m.err != nil {
+			return false
+		} else {
+			heap.Pop(&m.heap)
+		}
+	}
+	if len(m.heap) == 0 {
+		m.current = -1
+		return false
+	}
+	m.current = m.heap[0].index
+	m.rec = m.heap[0].reader.rec
+	return true
+}
+
+// Record returns the last record read, it is only valid until the next Read
+func (m *MergeReader) Record() Record {
+	return m.rec
+}
+
+// Index returns the index of the reader of the last record read, or of the
+// reader that failed
+func (m *MergeReader) Index() int {
+	return m.current
+}
+
+// Err returns the error of the reader that stopped reading, reading stops
+// when any of the readers fails
+func (m *MergeReader) Err() error {
+	return m.err
+}
+
+type readerIndex struct {
+	reader *Reader
+	index  int
+}
+
+type readerHeap []readerIndex
+
+func (h readerHeap) Len() int { return len(h) }
+
+func (h readerHeap) Less(i, j int) bool {
+	ti := h[i].reader.rec.Time
+	tj := h[j].reader.rec.Time
+	if ti.Equal(tj) {
+		return h[i].index < h[j].index
+	}
+	return ti.Before(tj)
+}
+
+func (h readerHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *readerHeap) Push(x interface{}) { *h = append(*h, x.(readerIndex)) }
+
+func (h *readerHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[0 : len(old)-1]
+	return x
+}
@@ -0,0 +1,31 @@
+package parsel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	var readers []*Reader
+	for _, content := range []string{
+		"2017-02-13T08:00:00Z\ta1\n2017-02-13T10:00:00Z\ta2",
+		"2017-02-13T09:00:00Z\tb1\n2017-02-13T10:00:00Z\tb2\n2017-02-13T11:00:00Z\tb3",
+		"",
+	} {
+		r, err := newReader(strings.NewReader(content), delimited('\t'), nil, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal("Invalid reader", err)
+		}
+		readers = append(readers, r)
+	}
+
+	var res []string
+	m := NewMergeReader(readers)
+	for m.Read() {
+		res = append(res, string(rune('a'+m.Index()))+":"+string(m.Record().Fields[0]))
+	}
+	if strings.Join(res, ", ") != "a:a1, b:b1, a:a2, b:b2, b:b3" {
+		t.Error("Expected a:a1, b:b1, a:a2, b:b2, b:b3 but got", strings.Join(res, ", "))
+	}
+}
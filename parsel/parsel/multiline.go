@@ -0,0 +1,83 @@
+package parsel
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// multiline joins lines not starting a record, such as stack traces, to the
+// previous record
+type multiline struct {
+	// continuation matches continuation lines, if nil any line without a
+	// time is a continuation
+	continuation *regexp.Regexp
+	event        []byte
+	lookahead    []byte
+	hasLookahead bool
+	// lookaheadNumber is the line number of lookahead
+	lookaheadNumber int
+	scratch         Record
+}
+
+func parseContinuation(continuation string) (*regexp.Regexp, error) {
+	if continuation == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(continuation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continuation %s: %v", continuation, err)
+	}
+	return re, nil
+}
+
+// nextEvent returns the next line joined with its continuation lines
+func (r *Reader) nextEvent() ([]byte, bool) {
+	m := r.multiline
+	if !m.hasLookahead {
+		line, ok := r.next()
+		if !ok {
+			return nil, false
+		}
+		m.lookahead = append(m.lookahead[0:0], line...)
+		m.lookaheadNumber = r.number
+	}
+	m.event = append(m.event[0:0], m.lookahead...)
+	m.hasLookahead = false
+	number := m.lookaheadNumber
+	for {
+		line, ok := r.next()
+		if !ok {
+			break
+		}
+		if !r.isContinuation(line) {
+			m.lookahead = append(m.lookahead[0:0], line...)
+			m.lookaheadNumber = r.number
+			m.hasLookahead = true
+			break
+		}
+		m.event = append(m.event, '\n')
+		m.event = append(m.event, line...)
+	}
+	r.number = number
+	return m.event, true
+}
+
+func (r *Reader) isContinuation(line []byte) bool {
+	if len(line) == 0 {
+		return true
+	}
+	if r.multiline.continuation != nil {
+		return r.multiline.continuation.Match(line)
+	}
+	return r.parser.parse(r.format, line, &r.multiline.scratch) != nil
+}
+
+// splitEvent splits an event into its first line and continuation lines
+func splitEvent(event []byte) ([]byte, []byte) {
+	newline := bytes.IndexByte(event, '\n')
+	if newline < 0 {
+		return event, nil
+	}
+	return event[0:newline], event[newline+1:]
+}
@@ -0,0 +1,49 @@
+package parsel
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const stackTrace = `2017-02-13T09:00:00Z	ERROR	failed
+java.lang.NullPointerException
+	at Foo.bar(Foo.java:12)
+2017-02-13T09:00:01Z	INFO	done
+2017-02-13T09:00:02Z	WARN	retry
+    caused by timeout`
+
+func TestMultiline(t *testing.T) {
+	r := multilineReader(t, stackTrace, nil)
+	var events []string
+	for r.Read() {
+		events = append(events, string(r.rec.Fields[0])+":"+string(r.rec.Continuation))
+	}
+	res := strings.Join(events, "|")
+	expect := "ERROR:java.lang.NullPointerException\n\tat Foo.bar(Foo.java:12)|INFO:|WARN:    caused by timeout"
+	if res != expect {
+		t.Errorf("Expected %q but got %q", expect, res)
+	}
+}
+
+func TestMultilineContinuation(t *testing.T) {
+	r := multilineReader(t, stackTrace, regexp.MustCompile(`^\s`))
+	var events []string
+	for r.Read() {
+		events = append(events, string(r.rec.Fields[0]))
+	}
+	// the exception line is neither a continuation nor a record
+	if strings.Join(events, ",") != "ERROR,INFO,WARN" {
+		t.Error("Expected ERROR,INFO,WARN but got", strings.Join(events, ","))
+	}
+}
+
+func multilineReader(t *testing.T, content string, continuation *regexp.Regexp) *Reader {
+	r, err := newReader(strings.NewReader(content), delimited('\t'), nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	r.multiline = &multiline{continuation: continuation}
+	return r
+}
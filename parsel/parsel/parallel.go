@@ -0,0 +1,227 @@
+package parsel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// size of the chunks a file is split into when read in parallel
+var chunkSize int64 = 4 * 1024 * 1024
+
+type chunk struct {
+	start int64
+	end   int64
+}
+
+type chunkJob struct {
+	chunk chunk
+	out   chan chunkResult
+}
+
+// chunkResult is what was read from a chunk, errors have line numbers
+// counting from the start of the chunk
+type chunkResult struct {
+	records []Record
+	errors  []chunkError
+	stats   Stats
+	// err stopped reading the chunk at line errLine
+	err     error
+	errLine int
+}
+
+// chunkError is a parse error after some of the records of a chunk
+type chunkError struct {
+	records int
+	err     *LineError
+}
+
+// parallelFile returns the file of a reader that can be read in parallel,
+// plain files that are not followed
+func parallelFile(r *Reader) (*os.File, bool) {
+	f, ok := r.closer.(*os.File)
+	if !ok {
+		return nil, false
+	}
+	stat, err := f.Stat()
+	if err != nil || !stat.Mode().IsRegular() {
+		return nil, false
+	}
+	return f, true
+}
+
+// ReadParallel calls emit with the remaining records matching filter until it
+// returns false. Plain files are split in chunks read by workers, other inputs,
+// multiline records and sequential filters are read sequentially.
+func (r *Reader) ReadParallel(workers int, filter *Filter, emit func(Record) bool) error {
+	// chunks could split multiline records
+	if f, ok := parallelFile(r); ok && workers > 1 && r.multiline == nil && !filter.Sequential() {
+		return readParallel(r, f, workers, filter.match, emit)
+	}
+	for r.Read() {
+		if filter.Match(r.rec) && !emit(r.rec) {
+			break
+		}
+	}
+	return r.Err()
+}
+
+// readParallel reads the rest of the file of r in newline aligned chunks,
+// parsing and filtering them with workers, and calls emit with the matching
+// records in file order until it returns false
+func readParallel(r *Reader, f *os.File, workers int, filter filterFn, emit func(Record) bool) error {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	chunks, err := splitChunks(f, start, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	jobs := make(chan chunkJob)
+	results := make(chan chan chunkResult, workers)
+	go func() {
+		defer close(results)
+		defer close(jobs)
+		for _, c := range chunks {
+			out := make(chan chunkResult, 1)
+			select {
+			case results <- out:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- chunkJob{c, out}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.out <- r.readChunk(f, job.chunk, filter)
+			}
+		}()
+	}
+
+	defer wg.Wait()
+	defer close(done)
+	for out := range results {
+		res := <-out
+		lines := r.stats.Lines
+		r.stats.Lines = r.stats.Lines + res.stats.Lines
+		r.stats.Records = r.stats.Records + res.stats.Records
+		r.stats.Errors = r.stats.Errors + res.stats.Errors
+		r.stats.Long = r.stats.Long + res.stats.Long
+		errors := res.errors
+		for i, record := range res.records {
+			for len(errors) > 0 && errors[0].records == i {
+				if err := r.chunkError(errors[0].err, lines); err != nil {
+					return err
+				}
+				errors = errors[1:]
+			}
+			if !emit(record) {
+				return nil
+			}
+		}
+		for _, e := range errors {
+			if err := r.chunkError(e.err, lines); err != nil {
+				return err
+			}
+		}
+		if res.err != nil {
+			r.err = fmt.Errorf("line %d: %s", r.lineNumber(lines+res.errLine), res.err)
+			return r.err
+		}
+	}
+	return nil
+}
+
+// chunkError reports a parse error of a chunk starting after lines
+func (r *Reader) chunkError(e *LineError, lines int) error {
+	if r.parseError == nil {
+		return nil
+	}
+	e.Line = r.lineNumber(lines + e.Line)
+	r.err = r.parseError(e)
+	return r.err
+}
+
+// readChunk reads the matching records of a chunk with the settings of r
+func (r *Reader) readChunk(f *os.File, c chunk, filter filterFn) chunkResult {
+	var res chunkResult
+	cr := Reader{
+		scanner: bufio.NewScanner(io.NewSectionReader(f, c.start, c.end-c.start)),
+		parser:  r.parser,
+		format:  r.format,
+		from:    r.from,
+		to:      r.to,
+		sorted:  r.sorted,
+		limit:   r.limit,
+	}
+	cr.limit.configure(cr.scanner, &cr.stats.Long)
+	if r.parseError != nil {
+		cr.parseError = func(e *LineError) error {
+			text := append([]byte(nil), e.Text...)
+			res.errors = append(res.errors, chunkError{len(res.records), &LineError{e.Line, text, e.Err}})
+			return nil
+		}
+	}
+	for cr.Read() {
+		if filter(cr.rec) {
+			res.records = append(res.records, cr.rec.Copy())
+		}
+	}
+	res.stats = cr.stats
+	if err := cr.scanner.Err(); err != nil {
+		res.err = err
+		res.errLine = cr.stats.Lines + 1
+	}
+	return res
+}
+
+// splitChunks splits the file from start to size into chunks starting at
+// the beginning of a line
+func splitChunks(f *os.File, start, size int64) ([]chunk, error) {
+	var chunks []chunk
+	for start < size {
+		end := start + chunkSize
+		if end >= size {
+			end = size
+		} else {
+			var err error
+			end, err = nextLine(f, end, size)
+			if err != nil {
+				return nil, err
+			}
+		}
+		chunks = append(chunks, chunk{start, end})
+		start = end
+	}
+	return chunks, nil
+}
+
+// nextLine returns the start of the first line starting at or after pos
+func nextLine(f *os.File, pos, size int64) (int64, error) {
+	in := bufio.NewReader(io.NewSectionReader(f, pos-1, size-pos+1))
+	skipped, err := in.ReadBytes('\n')
+	if err == io.EOF {
+		return size, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return pos - 1 + int64(len(skipped)), nil
+}
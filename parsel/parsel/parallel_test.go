@@ -0,0 +1,107 @@
+package parsel
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadParallel(t *testing.T) {
+	lines := sortedLog(200000)
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+
+	r, err := newReaderFile(file, delimited('\t'), nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	f, ok := parallelFile(r)
+	if !ok {
+		t.Fatal("Expected file to be read in parallel")
+	}
+
+	filter, _, err := parseFilter(debug, "\t", false, "1:0$")
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	var res []string
+	err = readParallel(r, f, 4, filter, func(r Record) bool {
+		res = append(res, string(r.Fields[0]))
+		return true
+	})
+	if err != nil {
+		t.Fatal("could not read file", err)
+	}
+	if len(res) != 20000 {
+		t.Fatal("Expected 20000 records but got", len(res))
+	}
+	for i, value := range res {
+		if value != strconv.Itoa(i*10) {
+			t.Fatal("Expected", i*10, "but got", value)
+		}
+	}
+}
+
+func TestReadParallelStop(t *testing.T) {
+	file := writeLog(t, sortedLog(200000))
+	defer os.Remove(file)
+
+	r, err := newReaderFile(file, delimited('\t'), nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	f, _ := parallelFile(r)
+
+	count := 0
+	err = readParallel(r, f, 4, parseFiltersOrFail(t), func(r Record) bool {
+		count = count + 1
+		return count < 10
+	})
+	if err != nil || count != 10 {
+		t.Error("Expected to stop after 10 records but got", count, err)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	lines := sortedLog(300000)
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal("could not open file", err)
+	}
+	defer f.Close()
+	stat, _ := f.Stat()
+
+	chunks, err := splitChunks(f, 0, stat.Size())
+	if err != nil {
+		t.Fatal("could not split file", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatal("Expected several chunks but got", len(chunks))
+	}
+	content := strings.Join(lines, "\n")
+	for i, c := range chunks {
+		if i > 0 && content[c.start-1] != '\n' {
+			t.Error("Expected chunk", i, "to start at a line")
+		}
+		if i > 0 && chunks[i-1].end != c.start {
+			t.Error("Expected chunk", i, "to follow the previous chunk")
+		}
+	}
+	if chunks[len(chunks)-1].end != stat.Size() {
+		t.Error("Expected chunks to cover the file")
+	}
+}
+
+func parseFiltersOrFail(t *testing.T) filterFn {
+	filter, err := ParseFilters(debug, "\t", false, nil)
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	return filter.match
+}
@@ -0,0 +1,159 @@
+package parsel
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Patterns finds any of many literal patterns in a line in a single pass,
+// with an Aho-Corasick automaton
+type Patterns struct {
+	patterns []string
+	// classes maps the bytes of the patterns to columns of delta, other
+	// bytes map to 0
+	classes [256]int32
+	width   int
+	// delta is the next state by state and byte class
+	delta []int32
+	// found is the pattern found when reaching a state, -1 for none
+	found []int32
+}
+
+// LoadPatterns reads patterns from a file, one per line
+func LoadPatterns(file string) (*Patterns, error) {
+	var patterns []string
+	err := readLookup(file, func(line []byte) {
+		patterns = append(patterns, string(line))
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read patterns %s", file)
+	}
+	if len(patterns) == 0 {
+		return nil, errors.Errorf("no patterns in %s", file)
+	}
+	return NewPatterns(patterns), nil
+}
+
+// NewPatterns compiles patterns, empty patterns are ignored
+func NewPatterns(patterns []string) *Patterns {
+	p := &Patterns{width: 1}
+	for _, pattern := range patterns {
+		for i := 0; i < len(pattern); i++ {
+			if p.classes[pattern[i]] == 0 {
+				p.classes[pattern[i]] = int32(p.width)
+				p.width = p.width + 1
+			}
+		}
+	}
+
+	// a trie of the patterns, -1 for missing transitions
+	p.addState()
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		state := int32(0)
+		for i := 0; i < len(pattern); i++ {
+			next := int(state)*p.width + int(p.classes[pattern[i]])
+			if p.delta[next] < 0 {
+				added := p.addState()
+				p.delta[next] = added
+			}
+			state = p.delta[next]
+		}
+		if p.found[state] < 0 {
+			p.found[state] = int32(len(p.patterns))
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+
+	// breadth first, replace missing transitions with the transitions of the
+	// longest suffix state, which is already complete
+	fail := make([]int32, len(p.found))
+	var queue []int32
+	for c := 0; c < p.width; c++ {
+		if next := p.delta[c]; next < 0 {
+			p.delta[c] = 0
+		} else {
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for c := 0; c < p.width; c++ {
+			suffix := p.delta[int(fail[state])*p.width+c]
+			next := p.delta[int(state)*p.width+c]
+			if next < 0 {
+				p.delta[int(state)*p.width+c] = suffix
+				continue
+			}
+			fail[next] = suffix
+			if p.found[next] < 0 {
+				p.found[next] = p.found[suffix]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return p
+}
+
+func (p *Patterns) addState() int32 {
+	for c := 0; c < p.width; c++ {
+		p.delta = append(p.delta, -1)
+	}
+	p.found = append(p.found, -1)
+	return int32(len(p.found) - 1)
+}
+
+// Find returns the index of the first pattern found in line, the one ending
+// first, -1 if none of them are found
+func (p *Patterns) Find(line []byte) int {
+	state := int32(0)
+	for _, b := range line {
+		state = p.delta[int(state)*p.width+int(p.classes[b])]
+		if p.found[state] >= 0 {
+			return int(p.found[state])
+		}
+	}
+	return -1
+}
+
+// Pattern returns the pattern with index i
+func (p *Patterns) Pattern(i int) string {
+	return p.patterns[i]
+}
+
+// Filter returns a filter matching records with a line containing any of
+// the patterns
+func (p *Patterns) Filter(trace io.Writer) *Filter {
+	return &Filter{match: func(r Record) bool {
+		i := p.Find(r.Line)
+		if trace != nil {
+			if i >= 0 {
+				fmt.Fprintln(trace, "filter.patterns: found", p.patterns[i])
+			} else {
+				fmt.Fprintln(trace, "filter.patterns: none found")
+			}
+		}
+		return i >= 0
+	}}
+}
+
+// AppendMatch appends the pattern found in the line of r to its fields,
+// named pattern for named records, it returns false if none is found
+func (p *Patterns) AppendMatch(r *Record) bool {
+	i := p.Find(r.Line)
+	if i < 0 {
+		return false
+	}
+	named := r.Names != nil && len(r.Names) == len(r.Fields)
+	// the fields may share their backing array with the reader
+	r.Fields = append(r.Fields[:len(r.Fields):len(r.Fields)], []byte(p.patterns[i]))
+	if named {
+		r.Names = append(r.Names[:len(r.Names):len(r.Names)], []byte("pattern"))
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package parsel
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestPatternsFind(t *testing.T) {
+	p := NewPatterns([]string{"he", "she", "his", "hers", ""})
+	for line, expect := range map[string]string{
+		"ushers":  "she",
+		"ahis":    "his",
+		"a hers":  "he",
+		"nothing": "",
+		"":        "",
+		"sh e":    "",
+	} {
+		found := ""
+		if i := p.Find([]byte(line)); i >= 0 {
+			found = p.Pattern(i)
+		}
+		if found != expect {
+			t.Errorf("Expected %q in %q but got %q", expect, line, found)
+		}
+	}
+}
+
+func TestPatternsFindRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	word := func(max int) string {
+		w := make([]byte, 1+random.Intn(max))
+		for i := range w {
+			w[i] = "abc\xff"[random.Intn(4)]
+		}
+		return string(w)
+	}
+	for round := 0; round < 200; round++ {
+		var patterns []string
+		for i := 0; i < 1+random.Intn(10); i++ {
+			patterns = append(patterns, word(4))
+		}
+		p := NewPatterns(patterns)
+		line := []byte(word(30))
+		found := ""
+		if i := p.Find(line); i >= 0 {
+			found = p.Pattern(i)
+		}
+		if expect := findNaive(patterns, line); found != expect {
+			t.Fatalf("Expected %q in %q with patterns %q but got %q", expect, line, patterns, found)
+		}
+	}
+}
+
+// findNaive finds the pattern ending first in line, the longest one if
+// several end there
+func findNaive(patterns []string, line []byte) string {
+	for end := 1; end <= len(line); end++ {
+		found := ""
+		for _, pattern := range patterns {
+			if len(pattern) > len(found) && bytes.HasSuffix(line[0:end], []byte(pattern)) {
+				found = pattern
+			}
+		}
+		if found != "" {
+			return found
+		}
+	}
+	return ""
+}
+
+func TestPatternsFilter(t *testing.T) {
+	file := writeLog(t, []string{"10.0.0.1", "", "E1234"})
+	defer os.Remove(file)
+	p, err := LoadPatterns(file)
+	if err != nil {
+		t.Fatal("could not load patterns", err)
+	}
+	filter, err := ParseFilters(debug, " ", false, []string{"GET"})
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	filter = filter.And(p.Filter(debug))
+
+	r := parseJoinRecord(t, "2017-03-01T16:02:04Z GET 10.0.0.1 /a")
+	if !filter.Match(r) {
+		t.Error("Expected a line with a pattern to match")
+	}
+	if !p.AppendMatch(&r) || joinedFields(r) != "GET 10.0.0.1 /a 10.0.0.1" {
+		t.Error("Expected the pattern to be appended but got", joinedFields(r))
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z POST 10.0.0.1 /a")
+	if filter.Match(r) {
+		t.Error("Expected the other filters to apply")
+	}
+	r = parseJoinRecord(t, "2017-03-01T16:02:04Z GET 10.0.0.2 /a")
+	if filter.Match(r) || p.AppendMatch(&r) {
+		t.Error("Expected a line without patterns not to match")
+	}
+}
+
+func TestPatternsEmpty(t *testing.T) {
+	file := writeLog(t, []string{"", " "})
+	defer os.Remove(file)
+	if _, err := LoadPatterns(file); err == nil {
+		t.Error("Expected error for a file without patterns")
+	}
+}
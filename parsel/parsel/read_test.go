@@ -1,4 +1,4 @@
-package cmd
+package parsel
 
 import (
 	"bufio"
@@ -12,11 +12,11 @@ func TestReadRange(t *testing.T) {
 
 	from, _ := time.Parse(time.RFC3339, "2017-02-13T09:00:00Z")
 	to, _ := time.Parse(time.RFC3339, "2017-02-13T10:00:00Z")
-	r := reader{
-		scanner:   bufio.NewScanner(strings.NewReader(str)),
-		delimiter: '\t',
-		from:      from,
-		to:        to,
+	r := Reader{
+		scanner: bufio.NewScanner(strings.NewReader(str)),
+		parser:  delimited('\t'),
+		from:    from,
+		to:      to,
 	}
 
 	res := readAllDates(&r)
@@ -30,11 +30,11 @@ func TestReadRangeInfiniteBegining(t *testing.T) {
 
 	from := time.Time{}
 	to, _ := time.Parse(time.RFC3339, "2017-02-13T10:00:00Z")
-	r := reader{
-		scanner:   bufio.NewScanner(strings.NewReader(str)),
-		delimiter: '\t',
-		from:      from,
-		to:        to,
+	r := Reader{
+		scanner: bufio.NewScanner(strings.NewReader(str)),
+		parser:  delimited('\t'),
+		from:    from,
+		to:      to,
 	}
 
 	res := readAllDates(&r)
@@ -48,11 +48,11 @@ func TestReadRangeInfiniteEnding(t *testing.T) {
 
 	from, _ := time.Parse(time.RFC3339, "2017-02-13T09:00:00Z")
 	to := time.Time{}
-	r := reader{
-		scanner:   bufio.NewScanner(strings.NewReader(str)),
-		delimiter: '\t',
-		from:      from,
-		to:        to,
+	r := Reader{
+		scanner: bufio.NewScanner(strings.NewReader(str)),
+		parser:  delimited('\t'),
+		from:    from,
+		to:      to,
 	}
 
 	res := readAllDates(&r)
@@ -64,9 +64,9 @@ func TestReadRangeInfiniteEnding(t *testing.T) {
 func TestReadRangeDefaults(t *testing.T) {
 	str := "2017-02-13T09:00:00Z"
 
-	r := reader{
-		scanner:   bufio.NewScanner(strings.NewReader(str)),
-		delimiter: '\t',
+	r := Reader{
+		scanner: bufio.NewScanner(strings.NewReader(str)),
+		parser:  delimited('\t'),
 	}
 
 	res := readAllDates(&r)
@@ -75,10 +75,10 @@ func TestReadRangeDefaults(t *testing.T) {
 	}
 }
 
-func readAllDates(r *reader) string {
+func readAllDates(r *Reader) string {
 	var dates []string
 	for r.Read() {
-		dates = append(dates, r.rec.timestamp.Format(time.RFC3339))
+		dates = append(dates, r.rec.Time.Format(time.RFC3339))
 	}
 	return strings.Join(dates, ", ")
 }
@@ -86,9 +86,9 @@ func readAllDates(r *reader) string {
 func TestReadFields(t *testing.T) {
 	str := "2017-02-13T09:00:00Z\tfirst\tsecond"
 
-	r := reader{
-		scanner:   bufio.NewScanner(strings.NewReader(str)),
-		delimiter: '\t',
+	r := Reader{
+		scanner: bufio.NewScanner(strings.NewReader(str)),
+		parser:  delimited('\t'),
 	}
 
 	res := readAllFields(&r)
@@ -97,10 +97,10 @@ func TestReadFields(t *testing.T) {
 	}
 }
 
-func readAllFields(r *reader) string {
+func readAllFields(r *Reader) string {
 	var fields []string
 	for r.Read() {
-		for _, record := range r.rec.records {
+		for _, record := range r.rec.Fields {
 			fields = append(fields, string(record))
 		}
 	}
@@ -0,0 +1,461 @@
+package parsel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Options configures how records are read
+type Options struct {
+	// From and To limit the records read to From inclusive and To exclusive,
+	// zero for no limit
+	From time.Time
+	To   time.Time
+	// Input is text, whitespace, csv, json or logfmt, text if empty
+	Input string
+	// Delimiter separates the fields, DefaultDelimiter(Input) if empty
+	Delimiter string
+	// TimeFormat is a Go layout, a preset or auto, rfc3339 if empty
+	TimeFormat string
+	// TimeKey is a comma separated list of keys of the time for json and
+	// logfmt input, ts or time if empty
+	TimeKey string
+	// Multiline joins lines without a time to the record before them
+	Multiline bool
+	// Continuation matches lines joined to the record before them, it
+	// implies Multiline
+	Continuation string
+	// MaxLine is the maximum length of lines in bytes, 0 for no limit
+	MaxLine int
+	// LongLines is truncate, skip or fail for lines longer than MaxLine,
+	// truncate if empty
+	LongLines string
+	// ParseError is called with lines that can not be parsed, they are
+	// skipped unless it returns an error, which stops reading and is returned
+	// by Err
+	ParseError func(err *LineError) error
+}
+
+// LineError is a line that could not be parsed
+type LineError struct {
+	// Line is the 1 based number of the line in the input
+	Line int
+	// Text of the line, only valid until the next Read
+	Text []byte
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: could not parse %q: %s", e.Line, e.Text, e.Err)
+}
+
+// Stats counts what a reader has read
+type Stats struct {
+	// Lines read, lines skipped when searching for From are not counted
+	Lines int
+	// Records parsed, within the time range or not
+	Records int
+	// Errors is the number of lines that could not be parsed
+	Errors int
+	// Long is the number of lines longer than the maximum line length that
+	// were truncated or skipped
+	Long int
+}
+
+// settings are options parsed once for all readers
+type settings struct {
+	parser       parser
+	format       *timeFormat
+	continuation *regexp.Regexp
+	limit        lineLimit
+}
+
+func (o Options) parse() (settings, error) {
+	delimiter := o.Delimiter
+	if delimiter == "" {
+		delimiter = DefaultDelimiter(o.Input)
+	}
+	p, err := parseInput(o.Input, delimiter, o.TimeKey)
+	if err != nil {
+		return settings{}, err
+	}
+	format, err := parseTimeFormat(o.TimeFormat)
+	if err != nil {
+		return settings{}, err
+	}
+	continuation, err := parseContinuation(o.Continuation)
+	if err != nil {
+		return settings{}, err
+	}
+	limit, err := parseLineLimit(o.MaxLine, o.LongLines)
+	if err != nil {
+		return settings{}, err
+	}
+	return settings{p, format, continuation, limit}, nil
+}
+
+// Validate checks the options without opening a reader
+func (o Options) Validate() error {
+	_, err := o.parse()
+	return err
+}
+
+func (o Options) open(newReader func(s settings) (*Reader, error)) (*Reader, error) {
+	s, err := o.parse()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(s)
+	if err != nil {
+		return nil, err
+	}
+	if o.Multiline || s.continuation != nil {
+		r.multiline = &multiline{continuation: s.continuation}
+	}
+	r.parseError = o.ParseError
+	r.limit = s.limit
+	r.limit.configure(r.scanner, &r.stats.Long)
+	return r, nil
+}
+
+// Open opens a reader of file, compressed files are decompressed and files
+// sorted by time are searched for From
+func Open(file string, o Options) (*Reader, error) {
+	return o.open(func(s settings) (*Reader, error) {
+		return newReaderFile(file, s.parser, s.format, o.From, o.To)
+	})
+}
+
+// OpenStdin opens a reader of standard input
+func OpenStdin(o Options) (*Reader, error) {
+	return o.open(func(s settings) (*Reader, error) {
+		return newReaderStdin(s.parser, s.format, o.From, o.To)
+	})
+}
+
+// Follow opens a reader that keeps reading file as it grows, like tail -F.
+// idle is called before waiting for more lines.
+func Follow(file string, o Options, idle func()) (*Reader, error) {
+	return o.open(func(s settings) (*Reader, error) {
+		return newReaderFollow(file, s.parser, s.format, o.From, o.To, idle)
+	})
+}
+
+// NewReader returns a reader of in
+func NewReader(in io.Reader, o Options) (*Reader, error) {
+	return o.open(func(s settings) (*Reader, error) {
+		return newReader(in, s.parser, s.format, o.From, o.To)
+	})
+}
+
+func newReaderFile(file string, p parser, format *timeFormat, from, to time.Time) (*Reader, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("coult not open %s: %s", file, err)
+	}
+	head := make([]byte, magicSize)
+	n, _ := f.ReadAt(head, 0)
+	if c := detectCompression(head[0:n], file); c != uncompressed {
+		in, err := decompress(f, c)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read %s compressed %s: %s", c, file, err)
+		}
+		r, err := newReader(in, p, format, from, to)
+		if err != nil {
+			in.Close()
+			f.Close()
+			return nil, err
+		}
+		r.closer = closers{in, f}
+		return r, nil
+	}
+	r, err := newReader(f, p, format, from, to)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	if r.format == autoTimeFormat {
+		head := make([]byte, 64*1024)
+		n, _ := f.ReadAt(head, 0)
+		if n < len(head) {
+			head = append(head[0:n], '\n')
+		}
+		r.format = detectTimeFormat(r.parser, headLines(head, timeSamples))
+		if r.format == nil {
+			r.format = defaultTimeFormat
+		}
+	}
+	if from != zero || to != zero {
+		r.sorted, err = seekFile(f, r.parser, r.format, from)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not seek in %s: %s", file, err)
+		}
+		r.offset, err = f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not seek in %s: %s", file, err)
+		}
+		if r.offset > 0 {
+			r.file = f
+			r.linesBefore = -1
+		}
+	}
+	return r, nil
+}
+
+func newReaderStdin(p parser, format *timeFormat, from, to time.Time) (*Reader, error) {
+	in, c := peekCompression(os.Stdin, "")
+	if c == uncompressed {
+		return newReader(in, p, format, from, to)
+	}
+	d, err := decompress(in, c)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s compressed stdin: %s", c, err)
+	}
+	r, err := newReader(d, p, format, from, to)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	r.closer = d
+	return r, nil
+}
+
+func newReader(in io.Reader, p parser, format *timeFormat, from, to time.Time) (*Reader, error) {
+	r := &Reader{
+		scanner: bufio.NewScanner(in),
+		parser:  p,
+		format:  format,
+		from:    from,
+		to:      to,
+	}
+	r.limit.configure(r.scanner, &r.stats.Long)
+	return r, nil
+}
+
+// Reader reads the records of an input within a time range
+type Reader struct {
+	scanner *bufio.Scanner
+	rec     Record
+	parser  parser
+	// format of the leading time, autoTimeFormat until detected
+	format  *timeFormat
+	pending [][]byte
+	from    time.Time
+	to      time.Time
+	// multiline is set when continuation lines are joined to records
+	multiline *multiline
+	// sorted is set when the input is known to be sorted by time, reading
+	// then stops after lateRecords records in a row at or after to
+	sorted bool
+	// late is the number of records in a row at or after to
+	late   int
+	closer io.Closer
+	limit  lineLimit
+	// parseError is called with lines that can not be parsed
+	parseError func(err *LineError) error
+	// err stops reading
+	err   error
+	stats Stats
+	// number is the number of the current line counting from where reading
+	// started
+	number int
+	// linesBefore is the number of lines before offset in file where reading
+	// started after seeking, -1 until counted
+	linesBefore int
+	offset      int64
+	file        *os.File
+}
+
+// Record returns the last record read, it is only valid until the next Read
+func (r *Reader) Record() Record {
+	return r.rec
+}
+
+// Sorted is true when the input is known to be sorted by time
+func (r *Reader) Sorted() bool {
+	return r.sorted
+}
+
+// Err returns the error that stopped reading, nil at the end of the input
+func (r *Reader) Err() error {
+	if r.err == nil && r.scanner.Err() != nil {
+		r.err = fmt.Errorf("line %d: %s", r.lineNumber(r.stats.Lines+1), r.scanner.Err())
+	}
+	return r.err
+}
+
+// Stats returns what has been read so far
+func (r *Reader) Stats() Stats {
+	return r.stats
+}
+
+// lineNumber returns the line number of the line n lines after the start of
+// reading, the lines before are only counted when needed after seeking
+func (r *Reader) lineNumber(n int) int {
+	if r.linesBefore < 0 {
+		r.linesBefore = 0
+		count, err := countLines(r.file, r.offset)
+		if err == nil {
+			r.linesBefore = count
+		}
+	}
+	return r.linesBefore + n
+}
+
+// countLines counts the lines in f before offset
+func countLines(f *os.File, offset int64) (int, error) {
+	buf := make([]byte, 64*1024)
+	count := 0
+	for pos := int64(0); pos < offset; {
+		size := int64(len(buf))
+		if offset-pos < size {
+			size = offset - pos
+		}
+		n, err := f.ReadAt(buf[0:size], pos)
+		count = count + bytes.Count(buf[0:n], []byte{'\n'})
+		pos = pos + int64(n)
+		if err != nil && pos < offset {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Read reads the next record within the time range, false at the end of the
+// input
+func (r *Reader) Read() bool {
+	more, found := r.readInternal()
+	for more && !found {
+		more, found = r.readInternal()
+	}
+	return found
+}
+
+var zero time.Time
+
+func (r *Reader) readInternal() (bool, bool) {
+	if r.format == autoTimeFormat {
+		r.detectFormat()
+	}
+	var line []byte
+	var ok bool
+	if r.multiline != nil {
+		line, ok = r.nextEvent()
+	} else {
+		line, ok = r.next()
+	}
+	if !ok || r.err != nil {
+		return false, false
+	}
+	if len(line) == 0 {
+		return true, false
+	}
+	first, continuation := splitEvent(line)
+	if r.multiline == nil {
+		first, continuation = line, nil
+	}
+	err := r.parser.parse(r.format, first, &r.rec)
+	if err != nil {
+		r.stats.Errors = r.stats.Errors + 1
+		if r.parseError != nil {
+			r.err = r.parseError(&LineError{Line: r.lineNumber(r.number), Text: line, Err: err})
+			if r.err != nil {
+				return false, false
+			}
+		}
+		return true, false
+	}
+	r.stats.Records = r.stats.Records + 1
+	r.rec.Line = line
+	r.rec.Continuation = continuation
+	if r.to != zero && !r.rec.Time.Before(r.to) {
+		r.late = r.late + 1
+		if r.sorted && r.late >= lateRecords {
+			return false, false
+		}
+		return true, false
+	}
+	r.late = 0
+	if r.from != zero && r.from.After(r.rec.Time) {
+		return true, false
+	}
+	return true, true
+}
+
+// next returns the next line, lines read while detecting the time format first
+func (r *Reader) next() ([]byte, bool) {
+	if len(r.pending) > 0 {
+		line := r.pending[0]
+		r.pending = r.pending[1:]
+		r.stats.Lines = r.stats.Lines + 1
+		r.number = r.stats.Lines
+		return line, true
+	}
+	if !r.scanner.Scan() {
+		return nil, false
+	}
+	r.stats.Lines = r.stats.Lines + 1
+	r.number = r.stats.Lines
+	return r.scanner.Bytes(), true
+}
+
+// detectFormat detects the time format from the first lines of the input,
+// falling back to the default format
+func (r *Reader) detectFormat() {
+	for len(r.pending) < timeSamples && r.scanner.Scan() {
+		r.pending = append(r.pending, append([]byte(nil), r.scanner.Bytes()...))
+	}
+	r.format = detectTimeFormat(r.parser, r.pending)
+	if r.format == nil {
+		r.format = defaultTimeFormat
+	}
+}
+
+func parse(delimiter byte, format *timeFormat, line []byte, rec *Record) error {
+	var last int
+	var err error
+	last, rec.Time, err = parseTime(string([]byte{delimiter}), format, line)
+	if err != nil {
+		return err
+	}
+	rec.Line = line
+	recs := rec.Fields[0:0]
+	current := 1
+	for last+current < len(line) {
+		if line[last+current] == delimiter {
+			recs = append(recs, line[last:last+current])
+			last = last + current + 1
+			current = 0
+		}
+		current = current + 1
+	}
+	// the last field ends with the line, there are none after a time alone
+	if last <= len(line) {
+		recs = append(recs, line[last:])
+	}
+	rec.Fields = recs
+	return nil
+}
+
+func parseTime(delimiter string, format *timeFormat, line []byte) (int, time.Time, error) {
+	if format == nil {
+		format = defaultTimeFormat
+	}
+	return format.parse(delimiter, line)
+}
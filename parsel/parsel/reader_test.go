@@ -0,0 +1,164 @@
+package parsel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	file := writeLog(t, sortedLog(1000))
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T10:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2017-02-13T10:03:00Z")
+	r, err := Open(file, Options{From: from, To: to})
+	if err != nil {
+		t.Fatal("Could not open", err)
+	}
+	defer r.Close()
+	if !r.Sorted() {
+		t.Error("Expected sorted file")
+	}
+	var res []string
+	for r.Read() {
+		res = append(res, string(r.Record().Fields[0]))
+	}
+	if r.Err() != nil {
+		t.Error("Expected no error but got", r.Err())
+	}
+	if strings.Join(res, ",") != "600,601,602" {
+		t.Error("Expected 600,601,602 but got", strings.Join(res, ","))
+	}
+}
+
+func TestOptionsInvalid(t *testing.T) {
+	for _, o := range []Options{
+		{Input: "xml"},
+		{Input: "csv", Delimiter: "ab"},
+		{TimeFormat: " "},
+		{Continuation: "("},
+	} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", o)
+		}
+		if _, err := NewReader(strings.NewReader(""), o); err == nil {
+			t.Errorf("Expected reader error for %+v", o)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var errors []string
+	r, err := NewReader(strings.NewReader("2017-02-13T10:00:00Z\ta\nbroken\n2017-02-13T10:00:01Z\tb"), Options{
+		ParseError: func(e *LineError) error {
+			errors = append(errors, fmt.Sprintf("%d:%s", e.Line, e.Text))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	count := 0
+	for r.Read() {
+		count = count + 1
+	}
+	if count != 2 || strings.Join(errors, ",") != "2:broken" {
+		t.Error("Expected 2 records and error for 2:broken but got", count, errors)
+	}
+	if r.Stats() != (Stats{Lines: 3, Records: 2, Errors: 1}) {
+		t.Errorf("Expected 3 lines, 2 records and 1 error but got %+v", r.Stats())
+	}
+}
+
+func TestParseErrorStrict(t *testing.T) {
+	r, err := NewReader(strings.NewReader("2017-02-13T10:00:00Z\ta\nbroken\n2017-02-13T10:00:01Z\tb"), Options{
+		ParseError: func(e *LineError) error {
+			return e
+		},
+	})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	count := 0
+	for r.Read() {
+		count = count + 1
+	}
+	if count != 1 || r.Err() == nil || r.Err().Error() != `line 2: could not parse "broken": parsing time "broken" as "2006-01-02T15:04:05Z07:00": cannot parse "broken" as "2006"` {
+		t.Error("Expected 1 record and error for line 2 but got", count, r.Err())
+	}
+}
+
+func TestParseErrorLineNumbers(t *testing.T) {
+	lines := sortedLog(1000)
+	lines[100] = "broken 100"
+	lines[300] = "x trace"
+	lines[700] = "broken 700"
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+	defer func(size int64) {
+		chunkSize = size
+	}(chunkSize)
+	chunkSize = 4096
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T10:50:00Z")
+	for _, c := range []struct {
+		options Options
+		expect  string
+	}{
+		{Options{}, "101:broken 100,301:x trace,701:broken 700"},
+		{Options{From: from}, "701:broken 700"},
+		{Options{Multiline: true, Continuation: "^x"}, "101:broken 100,701:broken 700"},
+	} {
+		for _, workers := range []int{1, 4} {
+			var errors []string
+			o := c.options
+			o.ParseError = func(e *LineError) error {
+				errors = append(errors, fmt.Sprintf("%d:%s", e.Line, e.Text))
+				return nil
+			}
+			r, err := Open(file, o)
+			if err != nil {
+				t.Fatal("Could not open", err)
+			}
+			err = r.ReadParallel(workers, &Filter{match: func(Record) bool { return true }}, func(Record) bool { return true })
+			r.Close()
+			if err != nil {
+				t.Fatal("Could not read", err)
+			}
+			if strings.Join(errors, ",") != c.expect {
+				t.Errorf("Expected %s but got %s with %d workers and %+v", c.expect, strings.Join(errors, ","), workers, c.options)
+			}
+		}
+	}
+}
+
+func TestReadParallelFilter(t *testing.T) {
+	file := writeLog(t, sortedLog(1000))
+	defer os.Remove(file)
+
+	filter, err := ParseFilters(nil, "\t", false, []string{"1:>990"})
+	if err != nil {
+		t.Fatal("Invalid filter", err)
+	}
+	for _, workers := range []int{1, 4} {
+		r, err := Open(file, Options{})
+		if err != nil {
+			t.Fatal("Could not open", err)
+		}
+		var res []string
+		err = r.ReadParallel(workers, filter, func(rec Record) bool {
+			res = append(res, string(rec.Fields[0]))
+			return true
+		})
+		r.Close()
+		if err != nil {
+			t.Fatal("Could not read", err)
+		}
+		if strings.Join(res, ",") != "991,992,993,994,995,996,997,998,999" {
+			t.Error("Expected 991 to 999 but got", strings.Join(res, ","), "with", workers, "workers")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package parsel reads log lines starting with a time as records, limited to
+// a time range and filtered with the filter language of the parsel command.
+package parsel
+
+import (
+	"time"
+)
+
+// Record is a parsed line
+type Record struct {
+	Time time.Time
+	Line []byte
+	// Fields of the line after the time
+	Fields [][]byte
+	// Names of the fields for inputs with named fields
+	Names [][]byte
+	// Continuation lines of multiline records, part of Line
+	Continuation []byte
+}
+
+// Copy copies a record so it is kept when the reader moves on
+func (r Record) Copy() Record {
+	size := len(r.Line)
+	for i, record := range r.Fields {
+		size = size + len(record)
+		if i < len(r.Names) {
+			size = size + len(r.Names[i])
+		}
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, r.Line...)
+	res := Record{Time: r.Time, Line: buf[0:len(r.Line)]}
+	if len(r.Continuation) > 0 {
+		res.Continuation = res.Line[len(r.Line)-len(r.Continuation):]
+	}
+	copyBytes := func(bs [][]byte) [][]byte {
+		if bs == nil {
+			return nil
+		}
+		res := make([][]byte, len(bs))
+		for i, b := range bs {
+			offset := len(buf)
+			buf = append(buf, b...)
+			res[i] = buf[offset:len(buf)]
+		}
+		return res
+	}
+	res.Fields = copyBytes(r.Fields)
+	res.Names = copyBytes(r.Names)
+	return res
+}
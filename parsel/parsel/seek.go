@@ -0,0 +1,112 @@
+package parsel
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// number of evenly spaced lines checked before trusting a file to be sorted
+const sortedSamples = 16
+
+// number of records in a row at or after to read before a sorted file is
+// trusted to have no more records before to, so a few lines out of order,
+// like lines from a skewed clock, do not end reading early
+const lateRecords = 1000
+
+// seekFile positions f at the first line with a timestamp at or after from.
+// It returns true if the file looks sorted by time, if it does not the file
+// is left at the start and should be scanned in full.
+func seekFile(f *os.File, p parser, format *timeFormat, from time.Time) (bool, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !stat.Mode().IsRegular() {
+		return false, nil
+	}
+	size := stat.Size()
+	sorted, err := isSorted(f, p, format, size)
+	if err != nil || !sorted {
+		return false, err
+	}
+	if from == zero {
+		return true, nil
+	}
+
+	var searchErr error
+	pos := sort.Search(int(size)+1, func(pos int) bool {
+		if searchErr != nil {
+			return true
+		}
+		_, t, found, err := lineAt(f, p, format, int64(pos), size)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return !found || !t.Before(from)
+	})
+	if searchErr != nil {
+		return false, searchErr
+	}
+	start, _, found, err := lineAt(f, p, format, int64(pos), size)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		start = size
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func isSorted(f *os.File, p parser, format *timeFormat, size int64) (bool, error) {
+	var last time.Time
+	for i := int64(0); i <= sortedSamples; i++ {
+		_, t, found, err := lineAt(f, p, format, size*i/sortedSamples, size)
+		if err != nil {
+			return false, err
+		}
+		if !found {
+			continue
+		}
+		if t.Before(last) {
+			return false, nil
+		}
+		last = t
+	}
+	return true, nil
+}
+
+// lineAt finds the first parsable line starting at or after pos, returning
+// its offset and timestamp
+func lineAt(f *os.File, p parser, format *timeFormat, pos, size int64) (int64, time.Time, bool, error) {
+	start := pos
+	if pos > 0 {
+		// start one byte early so a line starting exactly at pos is kept
+		start = pos - 1
+	}
+	in := bufio.NewReader(io.NewSectionReader(f, start, size-start))
+	skip := pos > 0
+	for {
+		line, err := in.ReadBytes('\n')
+		if !skip && len(line) > 0 {
+			var r Record
+			if p.parse(format, bytes.TrimRight(line, "\r\n"), &r) == nil {
+				return start, r.Time, true, nil
+			}
+		}
+		if err == io.EOF {
+			return size, time.Time{}, false, nil
+		} else if err != nil {
+			return 0, time.Time{}, false, err
+		}
+		skip = false
+		start = start + int64(len(line))
+	}
+}
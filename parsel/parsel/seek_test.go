@@ -0,0 +1,161 @@
+package parsel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLog(t *testing.T, lines []string) string {
+	f, err := ioutil.TempFile("", "parsel")
+	if err != nil {
+		t.Fatal("could not create file", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(lines, "\n")); err != nil {
+		t.Fatal("could not write file", err)
+	}
+	return f.Name()
+}
+
+func sortedLog(count int) []string {
+	start, _ := time.Parse(time.RFC3339, "2017-02-13T00:00:00Z")
+	lines := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		lines = append(lines, fmt.Sprintf("%s\t%d", start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i))
+	}
+	return lines
+}
+
+func TestSeekSorted(t *testing.T) {
+	file := writeLog(t, sortedLog(1000))
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T10:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2017-02-13T10:03:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, to)
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	if !r.sorted {
+		t.Error("Expected file to be detected as sorted")
+	}
+
+	res := readAllFields(r)
+	if res != "600, 601, 602" {
+		t.Error("Expected 600, 601, 602 but got", res)
+	}
+}
+
+func TestSeekSortedSkewed(t *testing.T) {
+	lines := sortedLog(5000)
+	// a line from a skewed clock between the matching lines
+	lines[601] = "2017-02-14T00:00:00Z\tskewed"
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T10:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2017-02-13T10:04:00Z")
+	for _, from := range []time.Time{from, {}} {
+		r, err := newReaderFile(file, delimited('\t'), nil, from, to)
+		if err != nil {
+			t.Fatal("could not open reader", err)
+		}
+		if !r.sorted {
+			t.Error("Expected file to be detected as sorted")
+		}
+		res := readAllFields(r)
+		r.Close()
+		if !strings.HasSuffix(res, "600, 602, 603") {
+			t.Error("Expected records up to 603 but got", res)
+		}
+	}
+}
+
+func TestSeekTimeOnly(t *testing.T) {
+	lines := sortedLog(100)
+	for i := range lines {
+		lines[i] = strings.Split(lines[i], "\t")[0]
+	}
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T01:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2017-02-13T01:02:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, to)
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	count := 0
+	for r.Read() {
+		if len(r.Record().Fields) != 0 {
+			t.Error("Expected no fields but got", r.Record().Fields)
+		}
+		count = count + 1
+	}
+	if r.Err() != nil || count != 2 {
+		t.Error("Expected 2 records but got", count, r.Err())
+	}
+}
+
+func TestSeekBeforeStart(t *testing.T) {
+	file := writeLog(t, sortedLog(3))
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2016-01-01T00:00:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+
+	res := readAllFields(r)
+	if res != "0, 1, 2" {
+		t.Error("Expected 0, 1, 2 but got", res)
+	}
+}
+
+func TestSeekAfterEnd(t *testing.T) {
+	file := writeLog(t, sortedLog(3))
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2018-01-01T00:00:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, time.Time{})
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+
+	res := readAllFields(r)
+	if res != "" {
+		t.Error("Expected nothing but got", res)
+	}
+}
+
+func TestSeekUnsorted(t *testing.T) {
+	lines := sortedLog(100)
+	lines[0], lines[99] = lines[99], lines[0]
+	file := writeLog(t, lines)
+	defer os.Remove(file)
+
+	from, _ := time.Parse(time.RFC3339, "2017-02-13T01:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2017-02-13T01:02:00Z")
+	r, err := newReaderFile(file, delimited('\t'), nil, from, to)
+	if err != nil {
+		t.Fatal("could not open reader", err)
+	}
+	defer r.Close()
+	if r.sorted {
+		t.Error("Expected file to be detected as unsorted")
+	}
+
+	res := readAllFields(r)
+	if res != "60, 61" {
+		t.Error("Expected 60, 61 but got", res)
+	}
+}
@@ -0,0 +1,194 @@
+package parsel
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// timeParts are the parts of the record time that can be compared, in the
+// time zone of the record
+var timeParts = map[string]func(time.Time) int{
+	"year":    func(t time.Time) int { return t.Year() },
+	"month":   func(t time.Time) int { return int(t.Month()) },
+	"day":     func(t time.Time) int { return t.Day() },
+	"weekday": func(t time.Time) int { return int(t.Weekday()) },
+	"hour":    func(t time.Time) int { return t.Hour() },
+	"minute":  func(t time.Time) int { return t.Minute() },
+	"second":  func(t time.Time) int { return t.Second() },
+}
+
+// timeNames are the names of weekdays and months, by their first three letters
+var timeNames = map[string]map[string]int{
+	"weekday": {"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6},
+	"month": {"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12},
+}
+
+var timeOperators = []string{">=", "<=", "!=", "=", "<", ">"}
+
+// parseTimeFilter parses a filter on the record time. A part of the time is
+// compared to a value (eg hour>=22) or to a list of values and ranges (eg
+// weekday=sat,sun or hour=22-6), within=5s matches records within 5 seconds
+// of the previous matching record and is returned as well.
+func parseTimeFilter(trace io.Writer, filter string) (filterFn, *within, error) {
+	end := strings.IndexAny(filter, "<>=!")
+	if end <= 0 {
+		return nil, nil, errors.Errorf("missing comparison in time filter %s", filter)
+	}
+	name := filter[0:end]
+	var op string
+	for _, o := range timeOperators {
+		if strings.HasPrefix(filter[end:], o) {
+			op = o
+			break
+		}
+	}
+	value := filter[end+len(op):]
+	if op == "" || value == "" {
+		return nil, nil, errors.Errorf("invalid comparison in time filter %s", filter)
+	}
+	if name == "within" {
+		if op != "=" {
+			return nil, nil, errors.Errorf("within must be followed by = in time filter %s", filter)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid duration in time filter %s", filter)
+		}
+		w := &within{trace: trace, d: d}
+		return w.match, w, nil
+	}
+	fn, err := parseTimeCompare(trace, name, op, value, filter)
+	return fn, nil, err
+}
+
+// parseTimeCompare compares the part name of the record time with op to value
+func parseTimeCompare(trace io.Writer, name, op, value, filter string) (filterFn, error) {
+	part, ok := timeParts[name]
+	if !ok {
+		return nil, errors.Errorf("unknown time filter %s, expected year, month, day, weekday, hour, minute, second or within", filter)
+	}
+	if op == "=" || op == "!=" {
+		ranges, err := parseTimeRanges(name, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid time filter %s", filter)
+		}
+		return func(r Record) bool {
+			v := part(r.Time)
+			res := false
+			for _, tr := range ranges {
+				if tr.contains(v) {
+					res = true
+					break
+				}
+			}
+			if op == "!=" {
+				res = !res
+			}
+			if trace != nil {
+				fmt.Fprintln(trace, "filter.time:", name, v, op, value, res)
+			}
+			return res
+		}, nil
+	}
+	n, err := parseTimePart(name, value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time filter %s", filter)
+	}
+	return func(r Record) bool {
+		v := part(r.Time)
+		var res bool
+		switch op {
+		case "<":
+			res = v < n
+		case "<=":
+			res = v <= n
+		case ">":
+			res = v > n
+		case ">=":
+			res = v >= n
+		}
+		if trace != nil {
+			fmt.Fprintln(trace, "filter.time:", name, v, op, value, res)
+		}
+		return res
+	}, nil
+}
+
+// timeRange is an inclusive range of time part values, ranges where from is
+// after to wrap around (eg hour=22-6 or weekday=fri-mon)
+type timeRange struct {
+	from int
+	to   int
+}
+
+func (t timeRange) contains(v int) bool {
+	if t.from <= t.to {
+		return t.from <= v && v <= t.to
+	}
+	return v >= t.from || v <= t.to
+}
+
+func parseTimeRanges(name, value string) ([]timeRange, error) {
+	var ranges []timeRange
+	for _, item := range strings.Split(value, ",") {
+		from, to := item, item
+		if dash := strings.Index(item, "-"); dash > 0 {
+			from, to = item[0:dash], item[dash+1:]
+		}
+		f, err := parseTimePart(name, from)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseTimePart(name, to)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, timeRange{f, t})
+	}
+	return ranges, nil
+}
+
+// parseTimePart parses a number or, for weekdays and months, a name
+func parseTimePart(name, value string) (int, error) {
+	if names, ok := timeNames[name]; ok && len(value) >= 3 {
+		if n, ok := names[strings.ToLower(value[0:3])]; ok {
+			return n, nil
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("%s is not a %s", value, name)
+	}
+	return n, nil
+}
+
+// within matches records within d of the previous record matching the rest
+// of the filter. Filters combined with within by and are checked before it,
+// so the records it checks match them, and the filter makes the last record
+// checked the previous record once it is done with it.
+type within struct {
+	trace    io.Writer
+	d        time.Duration
+	previous time.Time
+	// checked is set when the record being filtered is checked
+	checked bool
+}
+
+func (w *within) match(r Record) bool {
+	w.checked = true
+	diff := r.Time.Sub(w.previous)
+	if diff < 0 {
+		diff = -diff
+	}
+	res := !w.previous.IsZero() && diff <= w.d
+	if w.trace != nil {
+		fmt.Fprintln(w.trace, "filter.within:", w.d, diff, res)
+	}
+	return res
+}
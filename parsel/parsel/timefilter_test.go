@@ -0,0 +1,111 @@
+package parsel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFilterTimePart(t *testing.T) {
+	// records are at 2017-03-01T16:02:04Z, a wednesday
+	testFilter(t, "a", "0:hour>=16", true)
+	testFilter(t, "a", "0:hour>16", false)
+	testFilter(t, "a", "0:hour<=15", false)
+	testFilter(t, "a", "0:hour=16", true)
+	testFilter(t, "a", "0:hour!=16", false)
+	testFilter(t, "a", "0:!hour=16", false)
+	testFilter(t, "a", "0:hour=22-6", false)
+	testFilter(t, "a", "0:hour=9-17", true)
+	testFilter(t, "a", "0:minute=2", true)
+	testFilter(t, "a", "0:weekday=sat,sun", false)
+	testFilter(t, "a", "0:weekday=mon-fri", true)
+	testFilter(t, "a", "0:weekday=Wednesday", true)
+	testFilter(t, "a", "0:weekday=sat-tue", false)
+	testFilter(t, "a", "0:month>=feb", true)
+	testFilter(t, "a", "0:year<2017", false)
+}
+
+func TestFilterTimeInvalid(t *testing.T) {
+	for _, filter := range []string{"0:hour", "0:hour>", "0:week=1", "0:weekday=someday", "0:within>5s", "0:within=5"} {
+		if _, _, err := parseFilter(debug, " ", false, filter); err == nil {
+			t.Error("Expected error for", filter)
+		}
+	}
+}
+
+func TestFilterWithin(t *testing.T) {
+	for _, expression := range []string{"ERROR and 0:within=5s", "0:within=5s and ERROR"} {
+		filter, err := ParseFilters(debug, " ", false, []string{expression})
+		if err != nil {
+			t.Fatal("could not parse filter", err)
+		}
+		if !filter.Sequential() {
+			t.Error("Expected within filter to be sequential")
+		}
+		if res := filterWithin(t, filter); res != "ce" {
+			t.Error("Expected records within 5s of the previous error, ce but got", res, "for", expression)
+		}
+	}
+}
+
+func TestFilterWithinOnce(t *testing.T) {
+	var trace bytes.Buffer
+	filter, err := ParseFilters(&trace, " ", false, []string{"0:within=5s and ERROR"})
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	filterWithin(t, filter)
+	if n := strings.Count(trace.String(), "filter: "); n != 5 {
+		t.Error("Expected each record to be checked once but got", n)
+	}
+	if n := strings.Count(trace.String(), "filter.within:"); n != 4 {
+		t.Error("Expected within to check the errors but got", n)
+	}
+}
+
+func TestFilterWithinReset(t *testing.T) {
+	filter, err := ParseFilters(debug, " ", false, []string{"ERROR and 0:within=5s"})
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	filterWithin(t, filter)
+	var r Record
+	if err := parse(' ', nil, []byte("2017-03-01T16:00:22Z ERROR f"), &r); err != nil {
+		t.Fatal("could not parse line", err)
+	}
+	filter.Reset()
+	if filter.Match(r) {
+		t.Error("Expected the previous error to be forgotten")
+	}
+}
+
+func filterWithin(t *testing.T, filter *Filter) string {
+	lines := []string{
+		"2017-03-01T16:00:00Z ERROR a",
+		"2017-03-01T16:00:03Z INFO b",
+		"2017-03-01T16:00:04Z ERROR c",
+		"2017-03-01T16:00:20Z ERROR d",
+		"2017-03-01T16:00:21Z ERROR e",
+	}
+	var res string
+	for _, line := range lines {
+		var r Record
+		if err := parse(' ', nil, []byte(line), &r); err != nil {
+			t.Fatal("could not parse line", err)
+		}
+		if filter.Match(r) {
+			res = res + string(r.Fields[1])
+		}
+	}
+	return res
+}
+
+func TestFilterNotSequential(t *testing.T) {
+	filter, err := ParseFilters(debug, " ", false, []string{"0:hour>=22 or within"})
+	if err != nil {
+		t.Fatal("could not parse filter", err)
+	}
+	if filter.Sequential() {
+		t.Error("Expected filter not to be sequential")
+	}
+}
@@ -0,0 +1,183 @@
+package parsel
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type timeFormat struct {
+	name   string
+	layout string
+	// prefix is the number of bytes before the time, eg klog severity
+	prefix int
+	// words is the number of space separated words in the time
+	words int
+	// epoch is the unit of unix epoch times, 0 for layouts
+	epoch time.Duration
+}
+
+var defaultTimeFormat = &timeFormat{name: "rfc3339", layout: time.RFC3339, words: 1}
+
+// autoTimeFormat is detected from the first lines of each input
+var autoTimeFormat = &timeFormat{name: "auto"}
+
+// presets in the order they are tried when detecting the format
+var timeFormats = []*timeFormat{
+	defaultTimeFormat,
+	{name: "rfc3339nano", layout: time.RFC3339Nano, words: 1},
+	{name: "syslog", layout: time.Stamp, words: 3},
+	{name: "nginx", layout: "02/Jan/2006:15:04:05 -0700", words: 2},
+	{name: "klog", layout: "0102 15:04:05.000000", prefix: 1, words: 2},
+	{name: "unix", epoch: time.Second, words: 1},
+	{name: "unix-ms", epoch: time.Millisecond, words: 1},
+	{name: "unix-us", epoch: time.Microsecond, words: 1},
+	{name: "unix-ns", epoch: time.Nanosecond, words: 1},
+}
+
+// number of lines used to detect the time format
+const timeSamples = 10
+
+func parseTimeFormat(format string) (*timeFormat, error) {
+	if format == "" {
+		return defaultTimeFormat, nil
+	}
+	if strings.ToLower(format) == autoTimeFormat.name {
+		return autoTimeFormat, nil
+	}
+	for _, f := range timeFormats {
+		if strings.ToLower(format) == f.name {
+			return f, nil
+		}
+	}
+	words := len(strings.Fields(format))
+	if words == 0 {
+		return nil, fmt.Errorf("invalid time format %q", format)
+	}
+	return &timeFormat{name: format, layout: format, words: words}, nil
+}
+
+// detectTimeFormat returns the format parsing most of the lines, nil if no
+// format parses any of them
+func detectTimeFormat(p parser, lines [][]byte) *timeFormat {
+	var best *timeFormat
+	bestCount := 0
+	for _, f := range timeFormats {
+		count := 0
+		for _, line := range lines {
+			var r Record
+			err := p.parse(f, line, &r)
+			if err == nil && f.plausible(r.Time) {
+				count = count + 1
+			}
+		}
+		if count > bestCount {
+			best = f
+			bestCount = count
+		}
+	}
+	return best
+}
+
+// plausible rejects epoch times in the wrong unit
+func (f *timeFormat) plausible(t time.Time) bool {
+	if f.epoch == 0 {
+		return true
+	}
+	return t.Year() >= 2000 && t.Year() < 2100
+}
+
+// parse parses the time at the start of line, ending at the first space, tab
+// or delimiter after its words, and returns the position after its end
+func (f *timeFormat) parse(delimiter string, line []byte) (int, time.Time, error) {
+	if len(line) < f.prefix {
+		return 0, time.Time{}, fmt.Errorf("line too short for %s time", f.name)
+	}
+	end := f.prefix
+	for word := 0; word < f.words; word++ {
+		if word > 0 {
+			for end < len(line) && line[end] == ' ' {
+				end = end + 1
+			}
+		}
+		for end < len(line) && line[end] != ' ' && line[end] != '\t' && !hasPrefix(line[end:], delimiter) {
+			end = end + 1
+		}
+	}
+	value := string(line[f.prefix:end])
+
+	var date time.Time
+	var err error
+	if f.epoch != 0 {
+		date, err = parseEpoch(value, f.epoch)
+	} else {
+		date, err = time.Parse(f.layout, value)
+		if err == nil && date.Year() == 0 {
+			date = withYear(date, time.Now())
+		}
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return end + 1, date, nil
+}
+
+func hasPrefix(line []byte, prefix string) bool {
+	if len(prefix) == 0 || len(line) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if line[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// withYear sets the year of times without one to the year of now, or the year
+// before if that would place it in the future
+func withYear(t time.Time, now time.Time) time.Time {
+	res := t.AddDate(now.Year(), 0, 0)
+	if res.After(now.Add(24 * time.Hour)) {
+		res = t.AddDate(now.Year()-1, 0, 0)
+	}
+	return res
+}
+
+func parseEpoch(value string, unit time.Duration) (time.Time, error) {
+	whole, frac := value, ""
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		whole, frac = value[0:dot], value[dot+1:]
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch time %s: %v", value, err)
+	}
+	res := time.Unix(0, 0).UTC().Add(time.Duration(n) * unit)
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch time %s: %v", value, err)
+		}
+		res = res.Add(time.Duration(f * float64(unit)))
+	}
+	return res, nil
+}
+
+// headLines returns the first complete lines of head
+func headLines(head []byte, max int) [][]byte {
+	var lines [][]byte
+	for len(lines) < max {
+		newline := bytes.IndexByte(head, '\n')
+		if newline < 0 {
+			break
+		}
+		if newline > 0 {
+			lines = append(lines, head[0:newline])
+		}
+		head = head[newline+1:]
+	}
+	return lines
+}
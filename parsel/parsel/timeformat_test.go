@@ -0,0 +1,89 @@
+package parsel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormatPresets(t *testing.T) {
+	testTimeFormat(t, "rfc3339nano", "2017-02-13T09:16:57.123Z rest", "2017-02-13T09:16:57.123Z")
+	testTimeFormat(t, "nginx", "13/Feb/2017:09:16:57 +0100 rest", "2017-02-13T08:16:57Z")
+	testTimeFormat(t, "unix", "1486977417 rest", "2017-02-13T09:16:57Z")
+	testTimeFormat(t, "unix", "1486977417.5 rest", "2017-02-13T09:16:57.5Z")
+	testTimeFormat(t, "unix-ms", "1486977417123 rest", "2017-02-13T09:16:57.123Z")
+	testTimeFormat(t, "unix-us", "1486977417123456 rest", "2017-02-13T09:16:57.123456Z")
+	testTimeFormat(t, "unix-ns", "1486977417123456789 rest", "2017-02-13T09:16:57.123456789Z")
+	testTimeFormat(t, "2006-01-02 15:04:05", "2017-02-13 09:16:57 rest", "2017-02-13T09:16:57Z")
+}
+
+func TestTimeFormatWithoutYear(t *testing.T) {
+	now := time.Now()
+	format, _ := parseTimeFormat("syslog")
+	last, date, err := format.parse(" ", []byte("Feb  3 09:16:57 host rest"))
+	if err != nil {
+		t.Fatal("could not parse time", err)
+	}
+	if date.Month() != time.February || date.Day() != 3 || date.After(now.Add(24*time.Hour)) {
+		t.Error("Expected Feb 3 in the past but got", date)
+	}
+	if last != len("Feb  3 09:16:57 ") {
+		t.Error("Expected fields to start after the time but got", last)
+	}
+
+	format, _ = parseTimeFormat("klog")
+	_, date, err = format.parse(" ", []byte("W0213 09:16:57.000001 rest"))
+	if err != nil {
+		t.Fatal("could not parse time", err)
+	}
+	if date.Month() != time.February || date.Day() != 13 || date.Nanosecond() != 1000 {
+		t.Error("Expected Feb 13 but got", date)
+	}
+}
+
+func TestTimeFormatDetect(t *testing.T) {
+	testDetect(t, "2017-02-13T09:16:57Z a\n2017-02-13T09:16:58Z b", "rfc3339")
+	testDetect(t, "Feb 13 09:16:57 host a\nFeb 13 09:16:58 host b", "syslog")
+	testDetect(t, "1486977417 a\n1486977418 b", "unix")
+	testDetect(t, "1486977417123 a\n1486977418123 b", "unix-ms")
+	testDetect(t, "garbage\n13/Feb/2017:09:16:57 +0100 a", "nginx")
+}
+
+func TestReadAutoTimeFormat(t *testing.T) {
+	r, err := newReader(strings.NewReader("1486977417\tfirst\n1486977418\tsecond"), delimited('\t'), autoTimeFormat, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal("Invalid reader", err)
+	}
+	res := readAllFields(r)
+	if res != "first, second" {
+		t.Error("Expected first, second but got", res)
+	}
+}
+
+func testTimeFormat(t *testing.T, format, line, expect string) {
+	f, err := parseTimeFormat(format)
+	if err != nil {
+		t.Fatal("could not parse format", err)
+	}
+	var r Record
+	if err := parse(' ', f, []byte(line), &r); err != nil {
+		t.Fatal("could not parse line", line, err)
+	}
+	if r.Time.UTC().Format(time.RFC3339Nano) != expect {
+		t.Error("Expected", expect, "but got", r.Time.UTC().Format(time.RFC3339Nano), "for", line)
+	}
+	if len(r.Fields) != 1 || string(r.Fields[0]) != "rest" {
+		t.Errorf("Expected fields [rest] but got %q for %s", r.Fields, line)
+	}
+}
+
+func testDetect(t *testing.T, content, expect string) {
+	var lines [][]byte
+	for _, line := range strings.Split(content, "\n") {
+		lines = append(lines, []byte(line))
+	}
+	format := detectTimeFormat(delimited(' '), lines)
+	if format == nil || format.name != expect {
+		t.Error("Expected", expect, "but got", format, "for", content)
+	}
+}